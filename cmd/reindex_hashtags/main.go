@@ -0,0 +1,7 @@
+package main
+
+import "github.com/Verce11o/yata-comments/internal/app"
+
+func main() {
+	app.ReindexHashtags()
+}
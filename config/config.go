@@ -3,6 +3,7 @@ package config
 import (
 	"github.com/ilyakaznacheev/cleanenv"
 	"log"
+	"time"
 )
 
 type Config struct {
@@ -12,6 +13,16 @@ type Config struct {
 	MinioConfig MinioConfig    `yaml:"minio"`
 	Metrics     Metrics        `yaml:"metrics"`
 	RabbitMQ    RabbitMQ       `yaml:"rabbitmq"`
+	Images      Images         `yaml:"images"`
+	ImageGC     ImageGC        `yaml:"imageGC"`
+	Storage     Storage        `yaml:"storage"`
+	Scanner     Scanner        `yaml:"scanner"`
+	Search      Search         `yaml:"search"`
+	Directory   Directory      `yaml:"directory"`
+	Comments    Comments       `yaml:"comments"`
+	Gateway     Gateway        `yaml:"gateway"`
+	Health      Health         `yaml:"health"`
+	Moderation  Moderation     `yaml:"moderation"`
 }
 
 type PostgresConfig struct {
@@ -31,10 +42,81 @@ type RedisConfig struct {
 }
 
 type MinioConfig struct {
-	Endpoint  string `yaml:"Endpoint"`
-	AccessKey string `yaml:"MinioAccessKey"`
-	SecretKey string `yaml:"MinioSecretKey"`
-	SSL       bool   `yaml:"UseSSL"`
+	Endpoint         string `yaml:"Endpoint"`
+	AccessKey        string `yaml:"MinioAccessKey"`
+	SecretKey        string `yaml:"MinioSecretKey"`
+	SSL              bool   `yaml:"UseSSL"`
+	Region           string `yaml:"Region"`
+	Bucket           Bucket `yaml:"Bucket"`
+	QuarantineBucket string `yaml:"QuarantineBucket" env-default:"user-comments-quarantine"`
+}
+
+type Bucket struct {
+	Name      string          `yaml:"Name" env-default:"user-comments"`
+	Policy    string          `yaml:"Policy"`
+	Lifecycle BucketLifecycle `yaml:"Lifecycle"`
+}
+
+type BucketLifecycle struct {
+	UploadExpirationDays   int    `yaml:"UploadExpirationDays" env-default:"1"`
+	TransitionDays         int    `yaml:"TransitionDays"`
+	TransitionStorageClass string `yaml:"TransitionStorageClass"`
+}
+
+type Storage struct {
+	Backend    string            `yaml:"backend" env-default:"minio"`
+	Filesystem FilesystemStorage `yaml:"filesystem"`
+}
+
+type FilesystemStorage struct {
+	Root    string `yaml:"root" env-default:"./data/images"`
+	Addr    string `yaml:"addr" env-default:":8081"`
+	BaseURL string `yaml:"baseURL" env-default:"http://localhost:8081"`
+	Secret  string `yaml:"secret"`
+}
+
+type Scanner struct {
+	Backend  string        `yaml:"backend" env-default:"none"`
+	Network  string        `yaml:"network" env-default:"tcp"`
+	Address  string        `yaml:"address" env-default:"localhost:3310"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"30s"`
+	FailOpen bool          `yaml:"failOpen" env-default:"false"`
+}
+
+type Gateway struct {
+	Enabled bool   `yaml:"enabled" env-default:"true"`
+	Addr    string `yaml:"addr" env-default:":8082"`
+	CORS    CORS   `yaml:"cors"`
+}
+
+type CORS struct {
+	AllowedOrigins   []string      `yaml:"allowedOrigins"`
+	AllowedMethods   []string      `yaml:"allowedMethods" env-default:"GET,POST,PATCH,DELETE"`
+	AllowedHeaders   []string      `yaml:"allowedHeaders" env-default:"Content-Type,Authorization,Idempotency-Key,Upload-Token,Attachment,Alt-Text-Bin"`
+	ExposedHeaders   []string      `yaml:"exposedHeaders" env-default:"Grpc-Metadata-Entities-Bin,Grpc-Metadata-Attachments-Bin"`
+	AllowCredentials bool          `yaml:"allowCredentials" env-default:"false"`
+	MaxAge           time.Duration `yaml:"maxAge" env-default:"10m"`
+}
+
+type Comments struct {
+	MaxTextLength int `yaml:"maxTextLength" env-default:"2000"`
+	MaxTextBytes  int `yaml:"maxTextBytes" env-default:"16384"`
+}
+
+type Search struct {
+	Language string `yaml:"language" env-default:"english"`
+}
+
+type Directory struct {
+	Backend string          `yaml:"backend" env-default:"memory"`
+	URL     string          `yaml:"url"`
+	Timeout time.Duration   `yaml:"timeout" env-default:"2s"`
+	Users   []DirectoryUser `yaml:"users"`
+}
+
+type DirectoryUser struct {
+	ID       string `yaml:"id"`
+	Username string `yaml:"username"`
 }
 
 type RabbitMQ struct {
@@ -48,6 +130,37 @@ type RabbitMQ struct {
 	BindingKey   string `yaml:"bindingKey" env-required:"true"`
 }
 
+type Images struct {
+	AllowedTypes     []string       `yaml:"allowedTypes" env-default:"image/jpeg,image/png,image/gif,image/webp"`
+	MaxSize          int64          `yaml:"maxSize" env-default:"5242880"`
+	MaxWidth         int            `yaml:"maxWidth" env-default:"4096"`
+	MaxHeight        int            `yaml:"maxHeight" env-default:"4096"`
+	MaxAttachments   int            `yaml:"maxAttachments" env-default:"4"`
+	MaxAltTextLength int            `yaml:"maxAltTextLength" env-default:"1000"`
+	Unsanitizable    string         `yaml:"unsanitizable" env-default:"reject"`
+	Blocklist        ImageBlocklist `yaml:"blocklist"`
+	Variants         []ImageVariant `yaml:"variants"`
+}
+
+type ImageBlocklist struct {
+	Threshold int    `yaml:"threshold" env-default:"10"`
+	Action    string `yaml:"action" env-default:"reject"`
+}
+
+type ImageVariant struct {
+	Name      string `yaml:"name"`
+	MaxWidth  int    `yaml:"maxWidth"`
+	MaxHeight int    `yaml:"maxHeight"`
+}
+
+type ImageGC struct {
+	Enabled     bool          `yaml:"enabled" env-default:"true"`
+	DryRun      bool          `yaml:"dryRun" env-default:"false"`
+	Interval    time.Duration `yaml:"interval" env-default:"1h"`
+	GracePeriod time.Duration `yaml:"gracePeriod" env-default:"24h"`
+	BatchSize   int           `yaml:"batchSize" env-default:"500"`
+}
+
 type Metrics struct {
 	Jaeger Jaeger `yaml:"jaeger"`
 }
@@ -57,7 +170,22 @@ type Jaeger struct {
 }
 
 type App struct {
-	Port string `yaml:"port"`
+	Port       string `yaml:"port"`
+	Reflection bool   `yaml:"reflection" env-default:"false"`
+}
+
+// Moderation guards the ImageModeration RPCs, callers send Token in the moderation-token metadata.
+// The RPCs are refused while no token is configured.
+type Moderation struct {
+	Token string `yaml:"token" env:"MODERATION_TOKEN"`
+}
+
+// Health configures the dependency checks, DrainDelay is how long the service reports
+// NOT_SERVING on shutdown before it stops accepting requests.
+type Health struct {
+	Interval   time.Duration `yaml:"interval" env-default:"10s"`
+	Timeout    time.Duration `yaml:"timeout" env-default:"2s"`
+	DrainDelay time.Duration `yaml:"drainDelay" env-default:"5s"`
 }
 
 func LoadConfig() *Config {
@@ -1,12 +1,19 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/handler/gateway"
 	commentGRPC "github.com/Verce11o/yata-comments/internal/handler/grpc"
+	"github.com/Verce11o/yata-comments/internal/lib/commenttext"
+	"github.com/Verce11o/yata-comments/internal/lib/healthcheck"
+	"github.com/Verce11o/yata-comments/internal/lib/images"
 	"github.com/Verce11o/yata-comments/internal/lib/logger"
+	"github.com/Verce11o/yata-comments/internal/lib/scanner"
+	"github.com/Verce11o/yata-comments/internal/metrics/meter"
 	"github.com/Verce11o/yata-comments/internal/metrics/trace"
-	"github.com/Verce11o/yata-comments/internal/repository/minio"
 	"github.com/Verce11o/yata-comments/internal/repository/postgres"
 	"github.com/Verce11o/yata-comments/internal/repository/redis"
 	"github.com/Verce11o/yata-comments/internal/service"
@@ -14,36 +21,90 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/otel/propagation"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 func Run() {
 	log := logger.NewLogger()
 	cfg := config.LoadConfig()
 
 	tracer := trace.InitTracer("yata-comments")
+	meters := meter.InitMeter("yata-comments")
 
 	// Init repos
 	db := postgres.NewPostgres(cfg)
-	repo := postgres.NewCommentsPostgres(db, tracer.Tracer)
+	repo := postgres.NewCommentsPostgres(db, tracer.Tracer, cfg.Search.Language)
 
 	rdb := redis.NewRedis(cfg)
 	redisRepo := redis.NewCommentsRedis(rdb, tracer.Tracer)
 
-	minioClient := minio.NewMinio(cfg)
-	minioRepo := minio.NewCommentMinio(minioClient, tracer.Tracer)
+	storage, storageHandler := newStorage(cfg, tracer.Tracer)
+
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor(
+			otelgrpc.WithTracerProvider(tracer.Provider),
+			otelgrpc.WithPropagators(propagation.TraceContext{}),
+		)),
+		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor(
+			otelgrpc.WithTracerProvider(tracer.Provider),
+			otelgrpc.WithPropagators(propagation.TraceContext{}),
+		)),
+	)
+
+	imageProcessor := images.NewProcessor(cfg.Images)
+
+	imageScanner, err := scanner.NewScanner(log, cfg.Scanner)
+
+	if err != nil {
+		log.Fatalf("error while init malware scanner: %v", err)
+	}
+
+	commentService := service.NewCommentService(log, tracer.Tracer, repo, redisRepo, storage, imageProcessor, imageScanner, newDirectory(cfg, tracer.Tracer), commenttext.NewValidator(cfg.Comments))
+
+	commentHandler := commentGRPC.NewCommentGRPC(log, tracer.Tracer, commentService, cfg.Moderation)
+
+	pb.RegisterCommentsServer(s, commentHandler)
+	s.RegisterService(&commentGRPC.ImageUploadServiceDesc, commentHandler)
+	s.RegisterService(&commentGRPC.ImageModerationServiceDesc, commentHandler)
+	s.RegisterService(&commentGRPC.CommentStreamServiceDesc, commentHandler)
+	s.RegisterService(&commentGRPC.CommentChangesServiceDesc, commentHandler)
+	s.RegisterService(&commentGRPC.CommentSearchServiceDesc, commentHandler)
+	s.RegisterService(&commentGRPC.CommentHashtagsServiceDesc, commentHandler)
+
+	// every service registered so far follows the health of the required dependencies
+	services := []string{""}
+
+	for name := range s.GetServiceInfo() {
+		services = append(services, name)
+	}
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
 
-	s := grpc.NewServer(grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor(
-		otelgrpc.WithTracerProvider(tracer.Provider),
-		otelgrpc.WithPropagators(propagation.TraceContext{}),
-	)))
+	if cfg.App.Reflection {
+		reflection.Register(s)
+	}
 
-	commentService := service.NewCommentService(log, tracer.Tracer, repo, redisRepo, minioRepo)
+	healthChecker, err := healthcheck.NewChecker(log, healthServer, cfg.Health, services,
+		healthcheck.Check{Name: "postgres", Required: true, Ping: db.PingContext},
+		healthcheck.Check{Name: "redis", Required: true, Ping: func(ctx context.Context) error { return rdb.Ping(ctx).Err() }},
+		healthcheck.Check{Name: cfg.Storage.Backend, Required: true, Ping: storage.Ping},
+		healthcheck.Check{Name: "broker", Ping: healthcheck.DialCheck(net.JoinHostPort(cfg.RabbitMQ.Host, cfg.RabbitMQ.Port))},
+	)
 
-	pb.RegisterCommentsServer(s, commentGRPC.NewCommentGRPC(log, tracer.Tracer, commentService))
+	if err != nil {
+		log.Fatalf("error while init health checker: %v", err)
+	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.App.Port))
 
@@ -59,16 +120,97 @@ func Run() {
 
 	log.Info(fmt.Sprintf("server listening at %s", lis.Addr().String()))
 
+	var storageServer *http.Server
+
+	if storageHandler != nil {
+		storageServer = &http.Server{Addr: cfg.Storage.Filesystem.Addr, Handler: storageHandler}
+
+		go func() {
+			if err := storageServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Infof("error while listen storage server: %s", err)
+			}
+		}()
+
+		log.Info(fmt.Sprintf("storage server listening at %s", cfg.Storage.Filesystem.Addr))
+	}
+
+	var gatewayServer *http.Server
+
+	if cfg.Gateway.Enabled {
+		commentGateway, err := gateway.NewGateway(log, commentHandler, cfg.Gateway)
+
+		if err != nil {
+			log.Fatalf("error while init gateway: %v", err)
+		}
+
+		gatewayServer = &http.Server{Addr: cfg.Gateway.Addr, Handler: commentGateway.Handler()}
+
+		go func() {
+			if err := gatewayServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Infof("error while listen gateway server: %s", err)
+			}
+		}()
+
+		log.Info(fmt.Sprintf("gateway listening at %s", cfg.Gateway.Addr))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go healthChecker.Run(ctx)
+
+	if cfg.ImageGC.Enabled {
+		go service.NewImageGC(log, tracer.Tracer, repo, storage, imageProcessor, cfg.ImageGC).Run(ctx)
+	}
+
 	defer log.Sync()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	s.GracefulStop()
+	// probes stop routing traffic here, Shutdown waits the drain delay before connections are drained
+	healthChecker.Shutdown()
+
+	cancel()
+
+	if gatewayServer != nil {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+
+		if err := gatewayServer.Shutdown(shutdownCtx); err != nil {
+			log.Infof("error while shutdown gateway server: %s", err)
+		}
+
+		shutdownCancel()
+	}
+
+	// open comment streams would keep GracefulStop waiting forever
+	stopped := make(chan struct{})
+
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		s.Stop()
+	}
+
+	if storageServer != nil {
+		if err := storageServer.Shutdown(context.Background()); err != nil {
+			log.Infof("error while shutdown storage server: %s", err)
+		}
+	}
 
 	if err := db.Close(); err != nil {
 		log.Infof("error while close db: %s", err)
 	}
 
+	// flushes the last collection of metrics
+	if err := meters.Provider.Shutdown(context.Background()); err != nil {
+		log.Infof("error while shutdown meter provider: %s", err)
+	}
+
 }
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/lib/images"
+	"github.com/Verce11o/yata-comments/internal/lib/logger"
+	"github.com/Verce11o/yata-comments/internal/metrics/trace"
+	"github.com/Verce11o/yata-comments/internal/repository/postgres"
+	"github.com/google/uuid"
+	"os"
+)
+
+const backfillImageHashesBatch = 100
+
+// BackfillImageHashes computes the perceptual hashes of attachments stored before hashing was introduced,
+// so they can be blocklisted and matched against the blocklist.
+func BackfillImageHashes() {
+	log := logger.NewLogger()
+	cfg := config.LoadConfig()
+
+	tracer := trace.InitTracer("yata-comments-backfill-image-hashes")
+
+	db := postgres.NewPostgres(cfg)
+	repo := postgres.NewCommentsPostgres(db, tracer.Tracer, cfg.Search.Language)
+
+	storage, _ := newStorage(cfg, tracer.Tracer)
+
+	defer log.Sync()
+
+	ctx := context.Background()
+	afterID := uuid.Nil.String()
+	var hashed, failed int
+
+	// failed attachments stay unhashed, the cursor moves past them instead of fetching them again
+	for {
+		attachments, err := repo.GetUnhashedAttachments(ctx, afterID, backfillImageHashesBatch)
+
+		if err != nil {
+			log.Fatalf("cannot get attachments without perceptual hash: %v", err)
+		}
+
+		for _, attachment := range attachments {
+			afterID = attachment.AttachmentID.String()
+
+			data, err := storage.GetFile(ctx, attachment.ImageName, cfg.Images.MaxSize)
+
+			if err != nil {
+				log.Errorf("cannot get image %s of attachment %s: %v", attachment.ImageName, attachment.AttachmentID, err)
+				failed++
+				continue
+			}
+
+			hash, err := images.PerceptualHash(data)
+
+			if err != nil {
+				log.Errorf("cannot hash image %s of attachment %s: %v", attachment.ImageName, attachment.AttachmentID, err)
+				failed++
+				continue
+			}
+
+			if err := repo.SetAttachmentPerceptualHash(ctx, afterID, int64(hash)); err != nil {
+				log.Errorf("cannot set perceptual hash of attachment %s: %v", attachment.AttachmentID, err)
+				failed++
+				continue
+			}
+
+			hashed++
+		}
+
+		if len(attachments) < backfillImageHashesBatch {
+			break
+		}
+	}
+
+	log.Infof("hashed %d attachment images, %d failed", hashed, failed)
+
+	if err := db.Close(); err != nil {
+		log.Infof("error while close db: %s", err)
+	}
+
+	if failed > 0 {
+		log.Errorf("%d attachment images were not hashed, run the backfill again to retry them", failed)
+		log.Sync()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/repository"
+	"github.com/Verce11o/yata-comments/internal/repository/directory"
+	"go.opentelemetry.io/otel/trace"
+	"log"
+)
+
+const (
+	directoryBackendMemory = "memory"
+	directoryBackendHTTP   = "http"
+)
+
+func newDirectory(cfg *config.Config, tracer trace.Tracer) repository.UserDirectory {
+	switch cfg.Directory.Backend {
+	case directoryBackendMemory:
+		memoryDirectory, err := directory.NewMemoryDirectory(cfg.Directory.Users)
+
+		if err != nil {
+			log.Fatalf("error while load directory users: %v", err)
+		}
+
+		return memoryDirectory
+	case directoryBackendHTTP:
+		return directory.NewHTTPDirectory(cfg.Directory.URL, cfg.Directory.Timeout, tracer)
+	default:
+		log.Fatalf("unknown directory backend: %s", cfg.Directory.Backend)
+	}
+
+	return nil
+}
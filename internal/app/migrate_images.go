@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/lib/images"
+	"github.com/Verce11o/yata-comments/internal/lib/logger"
+	"github.com/Verce11o/yata-comments/internal/metrics/trace"
+	"github.com/Verce11o/yata-comments/internal/repository/postgres"
+	"github.com/Verce11o/yata-comments/internal/repository/redis"
+	"mime"
+	"os"
+	"path"
+)
+
+const migrateImagesBatch = 100
+
+// MigrateImages moves images stored under client-supplied names to server-generated keys.
+func MigrateImages() {
+	log := logger.NewLogger()
+	cfg := config.LoadConfig()
+
+	tracer := trace.InitTracer("yata-comments-migrate-images")
+
+	db := postgres.NewPostgres(cfg)
+	repo := postgres.NewCommentsPostgres(db, tracer.Tracer, cfg.Search.Language)
+
+	rdb := redis.NewRedis(cfg)
+	redisRepo := redis.NewCommentsRedis(rdb, tracer.Tracer)
+
+	storage, _ := newStorage(cfg, tracer.Tracer)
+
+	defer log.Sync()
+
+	ctx := context.Background()
+	var cursor string
+	var total, failed int
+
+	// several comments may share a legacy name, so old objects are removed only once none of them is left behind
+	legacyNames := make(map[string]bool)
+
+	// failed comments keep their legacy names, the keyset cursor moves past them instead of fetching them again
+	for {
+		comments, nextCursor, err := repo.GetLegacyImageComments(ctx, cursor, migrateImagesBatch)
+
+		if err != nil {
+			log.Fatalf("cannot get comments with legacy images: %v", err)
+		}
+
+		for _, comment := range comments {
+			newName := images.ObjectName(comment.UserID.String(), mime.TypeByExtension(path.Ext(comment.ImageName)))
+
+			if _, ok := legacyNames[comment.ImageName]; !ok {
+				legacyNames[comment.ImageName] = true
+			}
+
+			if err := storage.CopyFile(ctx, comment.ImageName, newName); err != nil {
+				log.Errorf("cannot copy image %s of comment %s: %v", comment.ImageName, comment.CommentID, err)
+				legacyNames[comment.ImageName] = false
+				failed++
+				continue
+			}
+
+			if err := repo.UpdateCommentImageName(ctx, comment.CommentID.String(), comment.ImageName, newName); err != nil {
+				log.Errorf("cannot update image name of comment %s: %v", comment.CommentID, err)
+				legacyNames[comment.ImageName] = false
+				failed++
+				continue
+			}
+
+			if err := redisRepo.DeleteCommentByIDCtx(ctx, comment.CommentID.String()); err != nil {
+				log.Errorf("cannot remove comment by id in redis: %v", err)
+			}
+
+			total++
+		}
+
+		if nextCursor == "" {
+			break
+		}
+
+		cursor = nextCursor
+	}
+
+	for name, migrated := range legacyNames {
+		if !migrated {
+			continue
+		}
+
+		if err := storage.DeleteFile(ctx, name); err != nil {
+			log.Errorf("cannot delete legacy image %s: %v", name, err)
+		}
+	}
+
+	log.Infof("migrated %d comment images, %d failed", total, failed)
+
+	if err := db.Close(); err != nil {
+		log.Infof("error while close db: %s", err)
+	}
+
+	if failed > 0 {
+		log.Errorf("%d comment images were not migrated, run the migration again to retry them", failed)
+		log.Sync()
+		os.Exit(1)
+	}
+}
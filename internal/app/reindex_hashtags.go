@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/lib/entities"
+	"github.com/Verce11o/yata-comments/internal/lib/logger"
+	"github.com/Verce11o/yata-comments/internal/metrics/trace"
+	"github.com/Verce11o/yata-comments/internal/repository/postgres"
+	"github.com/google/uuid"
+)
+
+const reindexHashtagsBatch = 500
+
+// ReindexHashtags extracts the hashtags of all comments again, it backfills comments created before
+// hashtags were stored and is safe to run repeatedly.
+func ReindexHashtags() {
+	log := logger.NewLogger()
+	cfg := config.LoadConfig()
+
+	tracer := trace.InitTracer("yata-comments-reindex-hashtags")
+
+	db := postgres.NewPostgres(cfg)
+	repo := postgres.NewCommentsPostgres(db, tracer.Tracer, cfg.Search.Language)
+
+	defer log.Sync()
+
+	ctx := context.Background()
+	afterID := uuid.Nil.String()
+	var batches int
+
+	for afterID != "" {
+		var err error
+
+		afterID, err = repo.ReindexHashtags(ctx, afterID, reindexHashtagsBatch, entities.Hashtags)
+
+		if err != nil {
+			log.Fatalf("cannot reindex comment hashtags: %v", err)
+		}
+
+		batches++
+	}
+
+	log.Infof("reindexed comment hashtags, %d batches", batches)
+
+	if err := db.Close(); err != nil {
+		log.Infof("error while close db: %s", err)
+	}
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/lib/logger"
+	"github.com/Verce11o/yata-comments/internal/metrics/trace"
+	"github.com/Verce11o/yata-comments/internal/repository/postgres"
+	"github.com/google/uuid"
+)
+
+const reindexSearchBatch = 500
+
+// ReindexSearch rebuilds the full-text search vectors of all comments in the configured language.
+func ReindexSearch() {
+	log := logger.NewLogger()
+	cfg := config.LoadConfig()
+
+	tracer := trace.InitTracer("yata-comments-reindex-search")
+
+	db := postgres.NewPostgres(cfg)
+	repo := postgres.NewCommentsPostgres(db, tracer.Tracer, cfg.Search.Language)
+
+	defer log.Sync()
+
+	ctx := context.Background()
+	afterID := uuid.Nil.String()
+	var batches int
+
+	for afterID != "" {
+		var err error
+
+		afterID, err = repo.ReindexSearch(ctx, afterID, reindexSearchBatch)
+
+		if err != nil {
+			log.Fatalf("cannot reindex comments: %v", err)
+		}
+
+		batches++
+	}
+
+	log.Infof("reindexed comments for search in %s, %d batches", cfg.Search.Language, batches)
+
+	if err := db.Close(); err != nil {
+		log.Infof("error while close db: %s", err)
+	}
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/repository"
+	"github.com/Verce11o/yata-comments/internal/repository/filesystem"
+	"github.com/Verce11o/yata-comments/internal/repository/minio"
+	"go.opentelemetry.io/otel/trace"
+	"log"
+	"net/http"
+)
+
+const (
+	storageBackendMinio      = "minio"
+	storageBackendFilesystem = "filesystem"
+)
+
+// newStorage builds the configured image storage, the handler is not nil when presigned URLs must be served by the app.
+func newStorage(cfg *config.Config, tracer trace.Tracer) (repository.StorageRepository, http.Handler) {
+	switch cfg.Storage.Backend {
+	case storageBackendMinio:
+		minioClient := minio.NewMinio(cfg)
+
+		buckets := []config.Bucket{cfg.MinioConfig.Bucket, {Name: cfg.MinioConfig.QuarantineBucket}}
+
+		for _, bucket := range buckets {
+			if err := minio.EnsureBucket(context.Background(), minioClient, cfg.MinioConfig.Region, bucket); err != nil {
+				log.Fatalf("error while bootstrap bucket: %v", err)
+			}
+		}
+
+		return minio.NewCommentMinio(minioClient, cfg.MinioConfig.Bucket.Name, cfg.MinioConfig.QuarantineBucket, tracer), nil
+	case storageBackendFilesystem:
+		root, secret := filesystem.NewFilesystem(cfg)
+		storage := filesystem.NewCommentFilesystem(root, cfg.Storage.Filesystem.BaseURL, secret, tracer)
+
+		return storage, storage.Handler()
+	default:
+		log.Fatalf("unknown storage backend: %s", cfg.Storage.Backend)
+	}
+
+	return nil, nil
+}
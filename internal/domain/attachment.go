@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const OriginalImage = "original"
+
+type Attachment struct {
+	AttachmentID uuid.UUID `json:"attachment_id" db:"attachment_id"`
+	CommentID    uuid.UUID `json:"comment_id" db:"comment_id"`
+	Position     int       `json:"position" db:"position"`
+	ImageName    string    `json:"image_name" db:"image_name"`
+	ContentType  string    `json:"content_type" db:"content_type"`
+	Size         int64     `json:"size" db:"size"`
+	Width        int       `json:"width" db:"width"`
+	Height       int       `json:"height" db:"height"`
+	AltText      string    `json:"alt_text" db:"alt_text"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	// PerceptualHash is empty for attachments stored before hashing was introduced.
+	PerceptualHash *int64 `json:"-" db:"perceptual_hash"`
+	// Flagged marks images that matched the blocklist and wait for a moderator.
+	Flagged bool `json:"flagged" db:"flagged"`
+
+	// URLs maps image variant names, including OriginalImage, to download URLs.
+	URLs map[string]string `json:"urls,omitempty" db:"-"`
+}
+
+// UploadAttachmentPrefix marks upload tokens among attachment IDs in AttachmentsInput.Order.
+const UploadAttachmentPrefix = "upload:"
+
+// AttachmentsInput carries the attachment changes of a create or update request.
+type AttachmentsInput struct {
+	// UploadTokens reference images uploaded ahead of the request, appended after an inline image.
+	UploadTokens []string
+	// Order lists the IDs of attachments to keep and new uploads as "upload:<token>".
+	Order []string
+	// AltTexts are applied positionally to the resulting attachments.
+	AltTexts []string
+}
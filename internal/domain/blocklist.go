@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// BlocklistEntry is a perceptual hash of an image moderators do not want to see uploaded again.
+type BlocklistEntry struct {
+	EntryID            uuid.UUID     `json:"entry_id" db:"entry_id"`
+	PerceptualHash     int64         `json:"perceptual_hash" db:"perceptual_hash"`
+	SourceAttachmentID uuid.NullUUID `json:"source_attachment_id" db:"source_attachment_id"`
+	Reason             string        `json:"reason" db:"reason"`
+	CreatedBy          uuid.UUID     `json:"created_by" db:"created_by"`
+	CreatedAt          time.Time     `json:"created_at" db:"created_at"`
+}
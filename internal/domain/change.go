@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// CommentChange is a compact change-log record, Type is one of CommentCreated, CommentUpdated or CommentDeleted.
+type CommentChange struct {
+	ChangeID  int64     `json:"change_id" db:"change_id"`
+	XactID    uint64    `json:"-" db:"xact_id"`
+	CommentID uuid.UUID `json:"comment_id" db:"comment_id"`
+	TweetID   uuid.UUID `json:"tweet_id" db:"tweet_id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Type      string    `json:"type" db:"change_type"`
+	ChangedAt time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// ChangeFilter narrows the change feed to a tweet, a user or both, an empty filter lists every change.
+type ChangeFilter struct {
+	TweetID string
+	UserID  string
+}
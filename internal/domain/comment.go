@@ -13,4 +13,8 @@ type Comment struct {
 	ImageName string    `json:"image_name" db:"image_name"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	Attachments []Attachment `json:"attachments,omitempty" db:"-"`
+	Mentions    []Mention    `json:"mentions,omitempty" db:"-"`
+	Entities    []Entity     `json:"entities,omitempty" db:"-"`
 }
@@ -0,0 +1,19 @@
+package domain
+
+const (
+	EntityHashtag = "hashtag"
+	EntityURL     = "url"
+	EntityMention = "mention"
+)
+
+// Entity is a structured part of comment text. Start and End are rune offsets, ByteStart and ByteEnd
+// are byte offsets into the UTF-8 text, both with the end exclusive.
+type Entity struct {
+	Type      string `json:"type"`
+	Text      string `json:"text"`
+	Value     string `json:"value"`
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
+	ByteStart int    `json:"byte_start"`
+	ByteEnd   int    `json:"byte_end"`
+}
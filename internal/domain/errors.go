@@ -0,0 +1,88 @@
+package domain
+
+import "errors"
+
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindNotFound
+	KindInvalid
+	KindConflict
+	KindAborted
+	KindUnavailable
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindInvalid:
+		return "invalid"
+	case KindConflict:
+		return "conflict"
+	case KindAborted:
+		return "aborted"
+	case KindUnavailable:
+		return "unavailable"
+	}
+	return "internal"
+}
+
+// Error is a failure classified by the repository that produced it. Cause keeps the driver error
+// for logs and errors.Is, Retryable tells whether the same request may succeed later.
+type Error struct {
+	Kind      ErrorKind
+	Message   string
+	Cause     error
+	Retryable bool
+}
+
+func (e *Error) Error() string {
+	if e.Cause == nil {
+		return e.Message
+	}
+
+	return e.Message + ": " + e.Cause.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+func NewError(kind ErrorKind, message string, cause error) *Error {
+	return &Error{Kind: kind, Message: message, Cause: cause, Retryable: kind == KindUnavailable || kind == KindAborted}
+}
+
+func NotFoundError(message string, cause error) *Error {
+	return NewError(KindNotFound, message, cause)
+}
+
+func UnavailableError(message string, cause error) *Error {
+	return NewError(KindUnavailable, message, cause)
+}
+
+func InternalError(message string, cause error) *Error {
+	return NewError(KindInternal, message, cause)
+}
+
+// ErrorKindOf returns the kind of the outermost Error in the chain of err, KindInternal if there is none.
+func ErrorKindOf(err error) ErrorKind {
+	var domainErr *Error
+
+	if errors.As(err, &domainErr) {
+		return domainErr.Kind
+	}
+
+	return KindInternal
+}
+
+func IsNotFound(err error) bool {
+	return ErrorKindOf(err) == KindNotFound
+}
+
+func IsRetryable(err error) bool {
+	var domainErr *Error
+
+	return errors.As(err, &domainErr) && domainErr.Retryable
+}
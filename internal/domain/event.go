@@ -0,0 +1,19 @@
+package domain
+
+import "github.com/google/uuid"
+
+const (
+	CommentCreated = "created"
+	CommentUpdated = "updated"
+	CommentDeleted = "deleted"
+)
+
+// CommentEvent is pushed to subscribers of a tweet, Cursor lets a reconnecting client resume after it.
+// EventID is generated by the publisher, so a retried publish is recognized and stored once.
+type CommentEvent struct {
+	EventID uuid.UUID `json:"event_id"`
+	Cursor  string    `json:"-"`
+	Type    string    `json:"type"`
+	TweetID uuid.UUID `json:"tweet_id"`
+	Comment Comment   `json:"comment"`
+}
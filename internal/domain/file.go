@@ -0,0 +1,9 @@
+package domain
+
+import "time"
+
+type StoredFile struct {
+	Name         string
+	Size         int64
+	LastModified time.Time
+}
@@ -0,0 +1,7 @@
+package domain
+
+type IdempotencyRecord struct {
+	Key         string `json:"key" db:"idempotency_key"`
+	CommentID   string `json:"comment_id" db:"comment_id"`
+	RequestHash string `json:"request_hash" db:"request_hash"`
+}
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Mention is a resolved @username in comment text, Start and End are rune offsets with End exclusive.
+type Mention struct {
+	CommentID uuid.UUID `json:"comment_id" db:"comment_id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Username  string    `json:"username" db:"username"`
+	Start     int       `json:"start" db:"start_offset"`
+	End       int       `json:"end" db:"end_offset"`
+}
+
+// MentionEvent notifies a user that a comment started mentioning them.
+type MentionEvent struct {
+	CommentID       uuid.UUID `json:"comment_id"`
+	TweetID         uuid.UUID `json:"tweet_id"`
+	AuthorID        uuid.UUID `json:"author_id"`
+	MentionedUserID uuid.UUID `json:"mentioned_user_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
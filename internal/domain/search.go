@@ -0,0 +1,14 @@
+package domain
+
+// SearchFilter narrows a search to a tweet, a user or both, an empty filter searches every comment.
+type SearchFilter struct {
+	TweetID string
+	UserID  string
+}
+
+type SearchResult struct {
+	Comment Comment
+	Rank    float32
+	// Snippet is an excerpt of the comment text with matches wrapped in <mark> tags.
+	Snippet string
+}
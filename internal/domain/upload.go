@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+type Upload struct {
+	Token        string `json:"token"`
+	UserID       string `json:"user_id"`
+	ObjectName   string `json:"object_name"`
+	OriginalName string `json:"original_name"`
+	Size         int64  `json:"size"`
+	Checksum     string `json:"checksum"`
+}
+
+type UploadURL struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"github.com/Verce11o/yata-comments/config"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type cors struct {
+	cfg     config.CORS
+	origins map[string]struct{}
+	any     bool
+}
+
+func newCORS(cfg config.CORS) *cors {
+	c := &cors{cfg: cfg, origins: make(map[string]struct{}, len(cfg.AllowedOrigins))}
+
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			c.any = true
+		}
+		c.origins[origin] = struct{}{}
+	}
+
+	return c
+}
+
+// wrap answers preflight requests and adds CORS headers for allowed origins.
+// Requests from other origins are served without them, so browsers reject the response.
+func (c *cors) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+
+		if origin == "" || !c.allowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Origin")
+
+		// a wildcard cannot be combined with credentials, the origin is echoed instead
+		if c.any && !c.cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if c.cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			header.Set("Access-Control-Allow-Methods", strings.Join(c.cfg.AllowedMethods, ", "))
+			header.Set("Access-Control-Allow-Headers", strings.Join(c.cfg.AllowedHeaders, ", "))
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(c.cfg.MaxAge.Seconds())))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if len(c.cfg.ExposedHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(c.cfg.ExposedHeaders, ", "))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *cors) allowed(origin string) bool {
+	if c.any {
+		return true
+	}
+
+	_, ok := c.origins[origin]
+
+	return ok
+}
@@ -0,0 +1,265 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/Verce11o/yata-comments/config"
+	pb "github.com/Verce11o/yata-protos/gen/go/comments"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+const (
+	OpenAPIPath     = "/openapi.json"
+	binHeaderSuffix = "-bin"
+)
+
+// requestHeaders are forwarded to the gRPC handlers as metadata under their lower-cased names,
+// other headers follow the grpc-gateway rules, e.g. Grpc-Metadata-Foo is forwarded as foo.
+var requestHeaders = map[string]string{
+	"Idempotency-Key": "idempotency-key",
+	"Upload-Token":    "upload-token",
+	"Attachment":      "attachment",
+	"Alt-Text-Bin":    "alt-text-bin",
+}
+
+type rpcFunc func(ctx context.Context, r *http.Request, pathParams map[string]string, marshaler runtime.Marshaler) (interface{}, error)
+
+// route binds an HTTP method and path to an RPC, the other fields describe it in the OpenAPI document.
+type route struct {
+	method  string
+	pattern string
+	rpc     string
+	call    rpcFunc
+
+	summary         string
+	body            string
+	query           []parameter
+	requestHeaders  []parameter
+	response        string
+	responseHeaders []header
+}
+
+// Gateway serves the Comments RPCs as a JSON API. Requests are handled in process by the gRPC
+// handlers, errors are written as google.rpc.Status JSON with the HTTP status mapped from the code.
+type Gateway struct {
+	log    *zap.SugaredLogger
+	server pb.CommentsServer
+	cfg    config.Gateway
+	mux    *runtime.ServeMux
+}
+
+func NewGateway(log *zap.SugaredLogger, server pb.CommentsServer, cfg config.Gateway) (*Gateway, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(matchRequestHeader),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+	)
+
+	g := &Gateway{log: log, server: server, cfg: cfg, mux: mux}
+
+	routes := g.routes()
+
+	for _, route := range routes {
+		if err := mux.HandlePath(route.method, route.pattern, g.handle(route.pattern, route.rpc, route.call)); err != nil {
+			return nil, err
+		}
+	}
+
+	document, err := newOpenAPIDocument(routes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mux.HandlePath(http.MethodGet, OpenAPIPath, serveOpenAPI(document)); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (g *Gateway) routes() []route {
+	return []route{
+		{
+			method: http.MethodPost, pattern: "/v1/tweets/{tweet_id}/comments", rpc: "/comments.Comments/CreateComment", call: g.createComment,
+			summary:         "Create a comment",
+			body:            "CreateCommentBody",
+			requestHeaders:  []parameter{idempotencyKeyParameter, uploadTokenParameter, altTextParameter},
+			response:        "commentsCreateCommentResponse",
+			responseHeaders: []header{attachmentsHeader},
+		},
+		{
+			method: http.MethodGet, pattern: "/v1/tweets/{tweet_id}/comments", rpc: "/comments.Comments/GetAllTweetComments", call: g.getAllTweetComments,
+			summary:         "List the comments of a tweet, oldest first",
+			query:           []parameter{{name: "cursor", description: "Cursor returned by the previous page."}},
+			response:        "commentsGetAllCommentsResponse",
+			responseHeaders: []header{entitiesHeader, attachmentsByCommentHeader},
+		},
+		{
+			method: http.MethodGet, pattern: "/v1/comments/{comment_id}", rpc: "/comments.Comments/GetComment", call: g.getComment,
+			summary:         "Get a comment",
+			response:        "commentsComment",
+			responseHeaders: []header{entitiesHeader, attachmentsHeader},
+		},
+		{
+			method: http.MethodPatch, pattern: "/v1/comments/{comment_id}", rpc: "/comments.Comments/UpdateComment", call: g.updateComment,
+			summary:         "Update a comment",
+			body:            "UpdateCommentBody",
+			requestHeaders:  []parameter{uploadTokenParameter, attachmentParameter, altTextParameter},
+			response:        "commentsComment",
+			responseHeaders: []header{entitiesHeader, attachmentsHeader},
+		},
+		{
+			method: http.MethodDelete, pattern: "/v1/comments/{comment_id}", rpc: "/comments.Comments/DeleteComment", call: g.deleteComment,
+			summary:  "Delete a comment",
+			query:    []parameter{{name: "user_id", format: "uuid", required: true}},
+			response: "commentsDeleteCommentResponse",
+		},
+	}
+}
+
+// Handler returns the gateway wrapped in the configured CORS policy. Incoming trace context is
+// extracted, so the gRPC handlers called in process continue the trace of the caller.
+func (g *Gateway) Handler() http.Handler {
+	return otelhttp.NewHandler(newCORS(g.cfg.CORS).wrap(g.mux), "comments-gateway",
+		otelhttp.WithPropagators(propagation.TraceContext{}),
+	)
+}
+
+func (g *Gateway) handle(pattern string, rpc string, call rpcFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		inbound, outbound := runtime.MarshalerForRequest(g.mux, r)
+
+		ctx, err := runtime.AnnotateIncomingContext(ctx, g.mux, r, rpc, runtime.WithHTTPPathPattern(pattern))
+		if err != nil {
+			runtime.HTTPError(ctx, g.mux, outbound, w, r, err)
+			return
+		}
+
+		// collects the headers the handlers set with grpc.SetHeader
+		var stream runtime.ServerTransportStream
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &stream)
+
+		resp, err := call(ctx, r, pathParams, inbound)
+
+		ctx = runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{HeaderMD: stream.Header(), TrailerMD: stream.Trailer()})
+
+		if err != nil {
+			runtime.HTTPError(ctx, g.mux, outbound, w, r, err)
+			return
+		}
+
+		data, err := outbound.Marshal(resp)
+		if err != nil {
+			g.log.Errorf("gateway %s: cannot marshal response: %v", rpc, err)
+			runtime.HTTPError(ctx, g.mux, outbound, w, r, status.Error(codes.Internal, "cannot marshal response"))
+			return
+		}
+
+		writeHeaderMetadata(w, stream.Header())
+		w.Header().Set("Content-Type", outbound.ContentType(resp))
+
+		if _, err := w.Write(data); err != nil {
+			g.log.Infof("gateway %s: cannot write response: %v", rpc, err)
+		}
+	}
+}
+
+func (g *Gateway) createComment(ctx context.Context, r *http.Request, pathParams map[string]string, marshaler runtime.Marshaler) (interface{}, error) {
+	var input pb.CreateCommentRequest
+
+	if err := decodeBody(r, marshaler, &input); err != nil {
+		return nil, err
+	}
+
+	input.TweetId = pathParams["tweet_id"]
+
+	return g.server.CreateComment(ctx, &input)
+}
+
+func (g *Gateway) getAllTweetComments(ctx context.Context, r *http.Request, pathParams map[string]string, _ runtime.Marshaler) (interface{}, error) {
+	return g.server.GetAllTweetComments(ctx, &pb.GetAllTweetCommentsRequest{
+		TweetId: pathParams["tweet_id"],
+		Cursor:  r.URL.Query().Get("cursor"),
+	})
+}
+
+func (g *Gateway) getComment(ctx context.Context, _ *http.Request, pathParams map[string]string, _ runtime.Marshaler) (interface{}, error) {
+	return g.server.GetComment(ctx, &pb.GetCommentRequest{CommentId: pathParams["comment_id"]})
+}
+
+func (g *Gateway) updateComment(ctx context.Context, r *http.Request, pathParams map[string]string, marshaler runtime.Marshaler) (interface{}, error) {
+	var input pb.UpdateCommentRequest
+
+	if err := decodeBody(r, marshaler, &input); err != nil {
+		return nil, err
+	}
+
+	input.CommentId = pathParams["comment_id"]
+
+	return g.server.UpdateComment(ctx, &input)
+}
+
+func (g *Gateway) deleteComment(ctx context.Context, r *http.Request, pathParams map[string]string, _ runtime.Marshaler) (interface{}, error) {
+	return g.server.DeleteComment(ctx, &pb.DeleteCommentRequest{
+		CommentId: pathParams["comment_id"],
+		UserId:    r.URL.Query().Get("user_id"),
+	})
+}
+
+// decodeBody reads the JSON request body into input, an empty body leaves input unchanged.
+func decodeBody(r *http.Request, marshaler runtime.Marshaler, input interface{}) error {
+	err := marshaler.NewDecoder(r.Body).Decode(input)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return status.Errorf(codes.InvalidArgument, "invalid request body: %v", err)
+	}
+
+	return nil
+}
+
+func matchRequestHeader(key string) (string, bool) {
+	if name, ok := requestHeaders[textproto.CanonicalMIMEHeaderKey(key)]; ok {
+		return name, true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// writeHeaderMetadata sends response metadata as Grpc-Metadata-* headers, as grpc-gateway does.
+// Binary -bin values are base64 encoded, the same way they are expected in requests.
+func writeHeaderMetadata(w http.ResponseWriter, md metadata.MD) {
+	for key, values := range md {
+		for _, value := range values {
+			if strings.HasSuffix(key, binHeaderSuffix) {
+				value = base64.StdEncoding.EncodeToString([]byte(value))
+			}
+
+			w.Header().Add(runtime.MetadataHeaderPrefix+key, value)
+		}
+	}
+}
+
+func serveOpenAPI(document []byte) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(document)
+	}
+}
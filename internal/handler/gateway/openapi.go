@@ -0,0 +1,270 @@
+package gateway
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// parameter is a query or header parameter of a route.
+type parameter struct {
+	name        string
+	format      string
+	description string
+	required    bool
+	repeated    bool
+}
+
+// header is a response header of a route.
+type header struct {
+	name        string
+	description string
+}
+
+var (
+	idempotencyKeyParameter = parameter{
+		name:        "Idempotency-Key",
+		description: "Replays the comment created earlier with the same key and payload.",
+	}
+	uploadTokenParameter = parameter{
+		name:        "Upload-Token",
+		description: "Tokens of images uploaded ahead of the request. Repeat the header for several values.",
+		repeated:    true,
+	}
+	attachmentParameter = parameter{
+		name:        "Attachment",
+		description: "Attachment IDs to keep and uploads as upload:<token>, in display order. Repeat the header for several values.",
+		repeated:    true,
+	}
+	altTextParameter = parameter{
+		name:        "Alt-Text-Bin",
+		format:      "byte",
+		description: "Alt texts applied to the attachments in order, each value must be base64 encoded. Repeat the header for several values.",
+		repeated:    true,
+	}
+
+	entitiesHeader = header{
+		name:        "Grpc-Metadata-Entities-Bin",
+		description: "Base64 encoded JSON with the hashtag, URL and mention entities of the returned comments.",
+	}
+	attachmentsHeader = header{
+		name:        "Grpc-Metadata-Attachments-Bin",
+		description: "Base64 encoded JSON with the attachments of the returned comment, their alt texts and variant download URLs.",
+	}
+	attachmentsByCommentHeader = header{
+		name:        "Grpc-Metadata-Attachments-Bin",
+		description: "Base64 encoded JSON object mapping comment_id to the attachments of the returned comments with alt texts and variant download URLs.",
+	}
+)
+
+var pathParameterPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+type openAPIDocument struct {
+	Swagger     string                                 `json:"swagger"`
+	Info        openAPIInfo                            `json:"info"`
+	Consumes    []string                               `json:"consumes"`
+	Produces    []string                               `json:"produces"`
+	Paths       map[string]map[string]openAPIOperation `json:"paths"`
+	Definitions map[string]*openAPISchema              `json:"definitions"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type openAPIOperation struct {
+	Summary     string                     `json:"summary"`
+	OperationID string                     `json:"operationId"`
+	Tags        []string                   `json:"tags"`
+	Parameters  []openAPIParameter         `json:"parameters"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Required    bool           `json:"required"`
+	Type        string         `json:"type,omitempty"`
+	Format      string         `json:"format,omitempty"`
+	Items       *openAPISchema `json:"items,omitempty"`
+	Schema      *openAPISchema `json:"schema,omitempty"`
+	Description string         `json:"description,omitempty"`
+}
+
+type openAPIResponse struct {
+	Description string                   `json:"description"`
+	Schema      *openAPISchema           `json:"schema,omitempty"`
+	Headers     map[string]openAPISchema `json:"headers,omitempty"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+}
+
+// newOpenAPIDocument describes routes as a Swagger 2.0 document, so the document always matches
+// the paths the gateway serves.
+func newOpenAPIDocument(routes []route) ([]byte, error) {
+	document := openAPIDocument{
+		Swagger: "2.0",
+		Info: openAPIInfo{
+			Title:       "yata comments",
+			Version:     "v1",
+			Description: "JSON API for the comments.Comments gRPC service.",
+		},
+		Consumes:    []string{"application/json"},
+		Produces:    []string{"application/json"},
+		Paths:       make(map[string]map[string]openAPIOperation),
+		Definitions: openAPIDefinitions(),
+	}
+
+	for _, route := range routes {
+		operations, ok := document.Paths[route.pattern]
+
+		if !ok {
+			operations = make(map[string]openAPIOperation)
+			document.Paths[route.pattern] = operations
+		}
+
+		operations[strings.ToLower(route.method)] = newOpenAPIOperation(route)
+	}
+
+	return json.MarshalIndent(document, "", "  ")
+}
+
+func newOpenAPIOperation(route route) openAPIOperation {
+	var parameters []openAPIParameter
+
+	for _, match := range pathParameterPattern.FindAllStringSubmatch(route.pattern, -1) {
+		parameters = append(parameters, openAPIParameter{Name: match[1], In: "path", Required: true, Type: "string", Format: "uuid"})
+	}
+
+	if route.body != "" {
+		parameters = append(parameters, openAPIParameter{Name: "body", In: "body", Required: true, Schema: definitionRef(route.body)})
+	}
+
+	for _, query := range route.query {
+		parameters = append(parameters, newOpenAPIParameter(query, "query"))
+	}
+
+	for _, header := range route.requestHeaders {
+		parameters = append(parameters, newOpenAPIParameter(header, "header"))
+	}
+
+	response := openAPIResponse{Description: "A successful response.", Schema: definitionRef(route.response)}
+
+	if len(route.responseHeaders) > 0 {
+		response.Headers = make(map[string]openAPISchema, len(route.responseHeaders))
+
+		for _, header := range route.responseHeaders {
+			response.Headers[header.name] = openAPISchema{Type: "string", Description: header.description}
+		}
+	}
+
+	return openAPIOperation{
+		Summary:     route.summary,
+		OperationID: "Comments_" + route.rpc[strings.LastIndex(route.rpc, "/")+1:],
+		Tags:        []string{"Comments"},
+		Parameters:  parameters,
+		Responses: map[string]openAPIResponse{
+			"200": response,
+			"default": {
+				Description: "An error response, the HTTP status is mapped from the gRPC code.",
+				Schema:      definitionRef("rpcStatus"),
+			},
+		},
+	}
+}
+
+func newOpenAPIParameter(p parameter, in string) openAPIParameter {
+	param := openAPIParameter{Name: p.name, In: in, Required: p.required, Type: "string", Format: p.format, Description: p.description}
+
+	if p.repeated {
+		param.Type = "array"
+		param.Format = ""
+		param.Items = &openAPISchema{Type: "string", Format: p.format}
+	}
+
+	return param
+}
+
+func definitionRef(name string) *openAPISchema {
+	return &openAPISchema{Ref: "#/definitions/" + name}
+}
+
+func openAPIDefinitions() map[string]*openAPISchema {
+	uuid := func() *openAPISchema { return &openAPISchema{Type: "string", Format: "uuid"} }
+	str := func() *openAPISchema { return &openAPISchema{Type: "string"} }
+
+	commentBody := func() *openAPISchema {
+		return &openAPISchema{
+			Type:     "object",
+			Required: []string{"user_id", "text"},
+			Properties: map[string]*openAPISchema{
+				"user_id": uuid(),
+				"text":    str(),
+				"image":   definitionRef("commentsImage"),
+			},
+		}
+	}
+
+	return map[string]*openAPISchema{
+		"commentsImage": {
+			Type: "object",
+			Properties: map[string]*openAPISchema{
+				"chunk":        {Type: "string", Format: "byte"},
+				"content_type": str(),
+				"name":         str(),
+			},
+		},
+		"CreateCommentBody": commentBody(),
+		"UpdateCommentBody": commentBody(),
+		"commentsCreateCommentResponse": {
+			Type:       "object",
+			Properties: map[string]*openAPISchema{"comment_id": uuid()},
+		},
+		"commentsComment": {
+			Type: "object",
+			Properties: map[string]*openAPISchema{
+				"comment_id": uuid(),
+				"tweet_id":   uuid(),
+				"user_id":    uuid(),
+				"text":       str(),
+				"created_at": {Type: "string", Format: "date-time"},
+			},
+		},
+		"commentsGetAllCommentsResponse": {
+			Type: "object",
+			Properties: map[string]*openAPISchema{
+				"comments": {Type: "array", Items: definitionRef("commentsComment")},
+				"cursor":   str(),
+			},
+		},
+		"commentsDeleteCommentResponse": {Type: "object"},
+		"protobufAny": {
+			Type:                 "object",
+			Properties:           map[string]*openAPISchema{"@type": str()},
+			AdditionalProperties: &openAPISchema{},
+		},
+		"rpcStatus": {
+			Type: "object",
+			Properties: map[string]*openAPISchema{
+				"code":    {Type: "integer", Format: "int32"},
+				"message": str(),
+				"details": {
+					Type:        "array",
+					Items:       definitionRef("protobufAny"),
+					Description: "google.rpc.ErrorInfo with the reason and, for invalid requests, google.rpc.BadRequest with field violations.",
+				},
+			},
+		},
+	}
+}
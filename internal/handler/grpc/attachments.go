@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// setAttachmentsHeader sends comment attachments with their variant URLs next to pb.Comment responses.
+// CreateComment, GetComment and UpdateComment send a JSON array, GetAllTweetComments a JSON object keyed by comment_id.
+func setAttachmentsHeader(ctx context.Context, value interface{}) error {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	return grpc.SetHeader(ctx, metadata.Pairs(attachmentsHeader, string(data)))
+}
+
+func attachmentsToList(attachments []domain.Attachment) []interface{} {
+	items := make([]interface{}, 0, len(attachments))
+
+	for _, attachment := range attachments {
+		urls := make(map[string]interface{}, len(attachment.URLs))
+
+		for variant, url := range attachment.URLs {
+			urls[variant] = url
+		}
+
+		items = append(items, map[string]interface{}{
+			"attachment_id": attachment.AttachmentID.String(),
+			"position":      attachment.Position,
+			"content_type":  attachment.ContentType,
+			"width":         attachment.Width,
+			"height":        attachment.Height,
+			"alt_text":      attachment.AltText,
+			"urls":          urls,
+		})
+	}
+
+	return items
+}
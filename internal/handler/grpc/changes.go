@@ -0,0 +1,88 @@
+package grpc
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+	"time"
+)
+
+type CommentChangesServer interface {
+	ListChanges(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error)
+}
+
+// CommentChangesServiceDesc describes the change feed used for incremental sync.
+//
+// ListChanges takes a Struct with optional tweet_id, user_id, token and limit and returns a Struct
+// with changes and next_token. Every change has change_type, comment_id, tweet_id, user_id and
+// changed_at. Clients store next_token and pass it on the following call, an empty token starts
+// from the beginning of the log. The limit defaults to 100 and larger values are capped at 500.
+var CommentChangesServiceDesc = grpc.ServiceDesc{
+	ServiceName: "comments.CommentChanges",
+	HandlerType: (*CommentChangesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListChanges",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(CommentChangesServer).ListChanges(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{
+					Server:     srv,
+					FullMethod: "/comments.CommentChanges/ListChanges",
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(CommentChangesServer).ListChanges(ctx, req.(*structpb.Struct))
+				}
+				return interceptor(ctx, in, info, handler)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+func (c *CommentGRPC) ListChanges(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error) {
+	ctx, span := c.tracer.Start(ctx, "ListChanges")
+	defer span.End()
+
+	if err := validateListChanges(input); err != nil {
+		return nil, grpc_errors.NewStatus(err, "ListChanges")
+	}
+
+	fields := input.GetFields()
+
+	filter := domain.ChangeFilter{
+		TweetID: fields["tweet_id"].GetStringValue(),
+		UserID:  fields["user_id"].GetStringValue(),
+	}
+
+	changes, nextToken, err := c.service.ListChanges(ctx, filter, fields["token"].GetStringValue(), int(fields["limit"].GetNumberValue()))
+
+	if err != nil {
+		c.log.Errorf("ListChanges: %v", err.Error())
+		return nil, grpc_errors.NewStatus(err, "ListChanges")
+	}
+
+	items := make([]interface{}, 0, len(changes))
+
+	for _, change := range changes {
+		items = append(items, map[string]interface{}{
+			"change_type": change.Type,
+			"comment_id":  change.CommentID.String(),
+			"tweet_id":    change.TweetID.String(),
+			"user_id":     change.UserID.String(),
+			"changed_at":  change.ChangedAt.Format(time.RFC3339Nano),
+		})
+	}
+
+	return structpb.NewStruct(map[string]interface{}{
+		"changes":    items,
+		"next_token": nextToken,
+	})
+}
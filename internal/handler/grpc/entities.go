@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"time"
+)
+
+// setEntitiesHeader sends comment entities next to pb.Comment responses, which have no field for them.
+// GetComment and UpdateComment send a JSON array of entities, GetAllTweetComments a JSON object keyed by comment_id.
+func setEntitiesHeader(ctx context.Context, value interface{}) error {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	return grpc.SetHeader(ctx, metadata.Pairs(entitiesHeader, string(data)))
+}
+
+func entitiesToList(entities []domain.Entity) []interface{} {
+	items := make([]interface{}, 0, len(entities))
+
+	for _, entity := range entities {
+		items = append(items, map[string]interface{}{
+			"type":       entity.Type,
+			"text":       entity.Text,
+			"value":      entity.Value,
+			"start":      entity.Start,
+			"end":        entity.End,
+			"byte_start": entity.ByteStart,
+			"byte_end":   entity.ByteEnd,
+		})
+	}
+
+	return items
+}
+
+func commentToMap(comment domain.Comment) map[string]interface{} {
+	mentions := make([]interface{}, 0, len(comment.Mentions))
+
+	for _, mention := range comment.Mentions {
+		mentions = append(mentions, map[string]interface{}{
+			"user_id":  mention.UserID.String(),
+			"username": mention.Username,
+			"start":    mention.Start,
+			"end":      mention.End,
+		})
+	}
+
+	return map[string]interface{}{
+		"comment_id":  comment.CommentID.String(),
+		"tweet_id":    comment.TweetID.String(),
+		"user_id":     comment.UserID.String(),
+		"text":        comment.Text,
+		"created_at":  comment.CreatedAt.Format(time.RFC3339Nano),
+		"mentions":    mentions,
+		"entities":    entitiesToList(comment.Entities),
+		"attachments": attachmentsToList(comment.Attachments),
+	}
+}
@@ -2,37 +2,58 @@ package grpc
 
 import (
 	"context"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/domain"
 	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
 	"github.com/Verce11o/yata-comments/internal/service"
 	pb "github.com/Verce11o/yata-protos/gen/go/comments"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	idempotencyKeyHeader = "idempotency-key"
+	uploadTokenHeader    = "upload-token"
+	attachmentHeader     = "attachment"
+	altTextHeader        = "alt-text-bin"
+	entitiesHeader       = "entities-bin"
+	attachmentsHeader    = "attachments-bin"
 )
 
 type CommentGRPC struct {
-	log     *zap.SugaredLogger
-	tracer  trace.Tracer
-	service service.CommentService
+	log        *zap.SugaredLogger
+	tracer     trace.Tracer
+	service    service.CommentService
+	moderation config.Moderation
 	pb.UnimplementedCommentsServer
 }
 
-func NewCommentGRPC(log *zap.SugaredLogger, tracer trace.Tracer, service service.CommentService) *CommentGRPC {
-	return &CommentGRPC{log: log, tracer: tracer, service: service}
+func NewCommentGRPC(log *zap.SugaredLogger, tracer trace.Tracer, service service.CommentService, moderation config.Moderation) *CommentGRPC {
+	return &CommentGRPC{log: log, tracer: tracer, service: service, moderation: moderation}
 }
 
 func (c *CommentGRPC) CreateComment(ctx context.Context, input *pb.CreateCommentRequest) (*pb.CreateCommentResponse, error) {
 	ctx, span := c.tracer.Start(ctx, "CreateComment")
 	defer span.End()
 
-	commentID, err := c.service.CreateComment(ctx, input)
+	if err := validateCreateComment(ctx, input); err != nil {
+		return nil, grpc_errors.NewStatus(err, "CreateComment")
+	}
+
+	comment, err := c.service.CreateComment(ctx, input, metadataValue(ctx, idempotencyKeyHeader), attachmentsInputFromContext(ctx))
 
 	if err != nil {
 		c.log.Errorf("CreateComment: %v", err.Error())
-		return nil, status.Errorf(grpc_errors.ParseGRPCErrStatusCode(err), "CreateComment: %v", err)
+		return nil, grpc_errors.NewStatus(err, "CreateComment")
+	}
+
+	if err := setAttachmentsHeader(ctx, attachmentsToList(comment.Attachments)); err != nil {
+		c.log.Errorf("CreateComment: cannot set attachments header: %v", err.Error())
 	}
 
-	return &pb.CreateCommentResponse{CommentId: commentID}, nil
+	return &pb.CreateCommentResponse{CommentId: comment.CommentID.String()}, nil
 
 }
 
@@ -40,11 +61,23 @@ func (c *CommentGRPC) GetComment(ctx context.Context, input *pb.GetCommentReques
 	ctx, span := c.tracer.Start(ctx, "GetComment")
 	defer span.End()
 
+	if err := validateGetComment(input); err != nil {
+		return nil, grpc_errors.NewStatus(err, "GetComment")
+	}
+
 	comment, err := c.service.GetComment(ctx, input.GetCommentId())
 
 	if err != nil {
 		c.log.Errorf("GetComment: %v", err.Error())
-		return nil, status.Errorf(grpc_errors.ParseGRPCErrStatusCode(err), "GetComment: %v", err)
+		return nil, grpc_errors.NewStatus(err, "GetComment")
+	}
+
+	if err := setEntitiesHeader(ctx, comment.Entities); err != nil {
+		c.log.Errorf("GetComment: cannot set entities header: %v", err.Error())
+	}
+
+	if err := setAttachmentsHeader(ctx, attachmentsToList(comment.Attachments)); err != nil {
+		c.log.Errorf("GetComment: cannot set attachments header: %v", err.Error())
 	}
 
 	return &pb.Comment{
@@ -59,25 +92,65 @@ func (c *CommentGRPC) GetAllTweetComments(ctx context.Context, input *pb.GetAllT
 	ctx, span := c.tracer.Start(ctx, "GetAllTweetComments")
 	defer span.End()
 
+	if err := validateGetAllTweetComments(input); err != nil {
+		return nil, grpc_errors.NewStatus(err, "GetAllComments")
+	}
+
 	comments, nextCursor, err := c.service.GetAllTweetComments(ctx, input)
 
 	if err != nil {
 		c.log.Errorf("GetAllComments: %v", err.Error())
-		return nil, status.Errorf(grpc_errors.ParseGRPCErrStatusCode(err), "GetAllComments: %v", err)
+		return nil, grpc_errors.NewStatus(err, "GetAllComments")
+	}
+
+	items := make([]*pb.Comment, 0, len(comments))
+	commentEntities := make(map[string][]domain.Entity, len(comments))
+	commentAttachments := make(map[string][]interface{}, len(comments))
+
+	for _, comment := range comments {
+		items = append(items, &pb.Comment{
+			TweetId:   comment.TweetID.String(),
+			UserId:    comment.UserID.String(),
+			CommentId: comment.CommentID.String(),
+			Text:      comment.Text,
+			CreatedAt: timestamppb.New(comment.CreatedAt),
+		})
+		commentEntities[comment.CommentID.String()] = comment.Entities
+		commentAttachments[comment.CommentID.String()] = attachmentsToList(comment.Attachments)
+	}
+
+	if err := setEntitiesHeader(ctx, commentEntities); err != nil {
+		c.log.Errorf("GetAllComments: cannot set entities header: %v", err.Error())
+	}
+
+	if err := setAttachmentsHeader(ctx, commentAttachments); err != nil {
+		c.log.Errorf("GetAllComments: cannot set attachments header: %v", err.Error())
 	}
 
-	return &pb.GetAllCommentsResponse{Comments: comments, Cursor: nextCursor}, nil
+	return &pb.GetAllCommentsResponse{Comments: items, Cursor: nextCursor}, nil
 }
 
 func (c *CommentGRPC) UpdateComment(ctx context.Context, input *pb.UpdateCommentRequest) (*pb.Comment, error) {
 	ctx, span := c.tracer.Start(ctx, "UpdateComment")
 	defer span.End()
 
-	comment, err := c.service.UpdateComment(ctx, input)
+	if err := validateUpdateComment(ctx, input); err != nil {
+		return nil, grpc_errors.NewStatus(err, "UpdateComment")
+	}
+
+	comment, err := c.service.UpdateComment(ctx, input, attachmentsInputFromContext(ctx))
 
 	if err != nil {
 		c.log.Errorf("UpdateComment: %v", err.Error())
-		return nil, status.Errorf(grpc_errors.ParseGRPCErrStatusCode(err), "UpdateComment: %v", err)
+		return nil, grpc_errors.NewStatus(err, "UpdateComment")
+	}
+
+	if err := setEntitiesHeader(ctx, comment.Entities); err != nil {
+		c.log.Errorf("UpdateComment: cannot set entities header: %v", err.Error())
+	}
+
+	if err := setAttachmentsHeader(ctx, attachmentsToList(comment.Attachments)); err != nil {
+		c.log.Errorf("UpdateComment: cannot set attachments header: %v", err.Error())
 	}
 
 	return &pb.Comment{
@@ -92,11 +165,43 @@ func (c *CommentGRPC) DeleteComment(ctx context.Context, input *pb.DeleteComment
 	ctx, span := c.tracer.Start(ctx, "DeleteComment")
 	defer span.End()
 
+	if err := validateDeleteComment(input); err != nil {
+		return nil, grpc_errors.NewStatus(err, "DeleteComment")
+	}
+
 	err := c.service.DeleteComment(ctx, input)
 
 	if err != nil {
-		return nil, status.Errorf(grpc_errors.ParseGRPCErrStatusCode(err), "DeleteComment: %v", err)
+		return nil, grpc_errors.NewStatus(err, "DeleteComment")
 	}
 
 	return &pb.DeleteCommentResponse{}, nil
 }
+
+// attachmentsInputFromContext reads attachment changes from the request metadata.
+// Alt texts are sent as binary headers as they may contain any unicode text.
+func attachmentsInputFromContext(ctx context.Context) domain.AttachmentsInput {
+	return domain.AttachmentsInput{
+		UploadTokens: metadataValues(ctx, uploadTokenHeader),
+		Order:        metadataValues(ctx, attachmentHeader),
+		AltTexts:     metadataValues(ctx, altTextHeader),
+	}
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	if values := metadataValues(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func metadataValues(ctx context.Context, key string) []string {
+	md, ok := metadata.FromIncomingContext(ctx)
+
+	if !ok {
+		return nil
+	}
+
+	return md.Get(key)
+}
@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type CommentHashtagsServer interface {
+	ListHashtagComments(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error)
+}
+
+// CommentHashtagsServiceDesc describes lookup of comments by hashtag.
+//
+// ListHashtagComments takes a Struct with tag, with or without the leading hash sign, and an optional
+// cursor and returns a Struct with comments and cursor. Tags match case-insensitively, comments are
+// ordered oldest first and carry their entities.
+var CommentHashtagsServiceDesc = grpc.ServiceDesc{
+	ServiceName: "comments.CommentHashtags",
+	HandlerType: (*CommentHashtagsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListHashtagComments",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(CommentHashtagsServer).ListHashtagComments(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{
+					Server:     srv,
+					FullMethod: "/comments.CommentHashtags/ListHashtagComments",
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(CommentHashtagsServer).ListHashtagComments(ctx, req.(*structpb.Struct))
+				}
+				return interceptor(ctx, in, info, handler)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+func (c *CommentGRPC) ListHashtagComments(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error) {
+	ctx, span := c.tracer.Start(ctx, "ListHashtagComments")
+	defer span.End()
+
+	if err := validateListHashtagComments(input); err != nil {
+		return nil, grpc_errors.NewStatus(err, "ListHashtagComments")
+	}
+
+	fields := input.GetFields()
+
+	comments, nextCursor, err := c.service.ListHashtagComments(ctx, fields["tag"].GetStringValue(), fields["cursor"].GetStringValue())
+
+	if err != nil {
+		c.log.Errorf("ListHashtagComments: %v", err.Error())
+		return nil, grpc_errors.NewStatus(err, "ListHashtagComments")
+	}
+
+	items := make([]interface{}, 0, len(comments))
+
+	for _, comment := range comments {
+		items = append(items, commentToMap(comment))
+	}
+
+	return structpb.NewStruct(map[string]interface{}{
+		"comments": items,
+		"cursor":   nextCursor,
+	})
+}
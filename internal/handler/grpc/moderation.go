@@ -0,0 +1,131 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+	"strconv"
+)
+
+const moderationTokenHeader = "moderation-token"
+
+type ImageModerationServer interface {
+	BlockImage(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error)
+	UnblockImage(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error)
+}
+
+// ImageModerationServiceDesc describes the image blocklist operations used by moderation tools.
+//
+// BlockImage takes a Struct with attachment_id, moderator_id and reason and returns a Struct with
+// entry_id and perceptual_hash. UnblockImage takes a Struct with attachment_id and returns a Struct
+// with the number of removed entries. Both require the configured token in the moderation-token metadata.
+var ImageModerationServiceDesc = grpc.ServiceDesc{
+	ServiceName: "comments.ImageModeration",
+	HandlerType: (*ImageModerationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "BlockImage",
+			Handler:    moderationHandler("BlockImage", ImageModerationServer.BlockImage),
+		},
+		{
+			MethodName: "UnblockImage",
+			Handler:    moderationHandler("UnblockImage", ImageModerationServer.UnblockImage),
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+func moderationHandler(method string, call func(ImageModerationServer, context.Context, *structpb.Struct) (*structpb.Struct, error)) func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(structpb.Struct)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(ImageModerationServer), ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/comments.ImageModeration/" + method,
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(ImageModerationServer), ctx, req.(*structpb.Struct))
+		}
+		return interceptor(ctx, in, info, handler)
+	}
+}
+
+func (c *CommentGRPC) BlockImage(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error) {
+	ctx, span := c.tracer.Start(ctx, "BlockImage")
+	defer span.End()
+
+	if err := c.authorizeModeration(ctx); err != nil {
+		c.log.Warnf("BlockImage: %v", err.Error())
+		return nil, grpc_errors.NewStatus(err, "BlockImage")
+	}
+
+	if err := validateBlockImage(input); err != nil {
+		return nil, grpc_errors.NewStatus(err, "BlockImage")
+	}
+
+	fields := input.GetFields()
+
+	entry, err := c.service.BlockImage(
+		ctx,
+		fields["attachment_id"].GetStringValue(),
+		fields["moderator_id"].GetStringValue(),
+		fields["reason"].GetStringValue(),
+	)
+
+	if err != nil {
+		c.log.Errorf("BlockImage: %v", err.Error())
+		return nil, grpc_errors.NewStatus(err, "BlockImage")
+	}
+
+	// the hash is sent as hex, a Struct number cannot hold 64 bits
+	return structpb.NewStruct(map[string]interface{}{
+		"entry_id":        entry.EntryID.String(),
+		"perceptual_hash": strconv.FormatUint(uint64(entry.PerceptualHash), 16),
+	})
+}
+
+func (c *CommentGRPC) UnblockImage(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error) {
+	ctx, span := c.tracer.Start(ctx, "UnblockImage")
+	defer span.End()
+
+	if err := c.authorizeModeration(ctx); err != nil {
+		c.log.Warnf("UnblockImage: %v", err.Error())
+		return nil, grpc_errors.NewStatus(err, "UnblockImage")
+	}
+
+	if err := validateUnblockImage(input); err != nil {
+		return nil, grpc_errors.NewStatus(err, "UnblockImage")
+	}
+
+	removed, err := c.service.UnblockImage(ctx, input.GetFields()["attachment_id"].GetStringValue())
+
+	if err != nil {
+		c.log.Errorf("UnblockImage: %v", err.Error())
+		return nil, grpc_errors.NewStatus(err, "UnblockImage")
+	}
+
+	return structpb.NewStruct(map[string]interface{}{"removed": removed})
+}
+
+// authorizeModeration checks the moderation token of the caller, moderation is refused while none is configured.
+func (c *CommentGRPC) authorizeModeration(ctx context.Context) error {
+	if c.moderation.Token == "" {
+		return fmt.Errorf("%w: moderation token is not configured", grpc_errors.ErrPermissionDenied)
+	}
+
+	token := metadataValue(ctx, moderationTokenHeader)
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.moderation.Token)) != 1 {
+		return fmt.Errorf("%w: invalid moderation token", grpc_errors.ErrPermissionDenied)
+	}
+
+	return nil
+}
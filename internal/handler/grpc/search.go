@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type CommentSearchServer interface {
+	SearchComments(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error)
+}
+
+// CommentSearchServiceDesc describes full-text search over comments.
+//
+// SearchComments takes a Struct with query, optional tweet_id, user_id, cursor and limit and returns
+// a Struct with results and cursor. Results are ordered by rank and carry the comment fields, rank and
+// a snippet with matches wrapped in <mark> tags. The query accepts web search syntax: quoted phrases,
+// OR and a leading minus to exclude a word.
+var CommentSearchServiceDesc = grpc.ServiceDesc{
+	ServiceName: "comments.CommentSearch",
+	HandlerType: (*CommentSearchServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SearchComments",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(CommentSearchServer).SearchComments(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{
+					Server:     srv,
+					FullMethod: "/comments.CommentSearch/SearchComments",
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(CommentSearchServer).SearchComments(ctx, req.(*structpb.Struct))
+				}
+				return interceptor(ctx, in, info, handler)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+func (c *CommentGRPC) SearchComments(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error) {
+	ctx, span := c.tracer.Start(ctx, "SearchComments")
+	defer span.End()
+
+	if err := validateSearchComments(input); err != nil {
+		return nil, grpc_errors.NewStatus(err, "SearchComments")
+	}
+
+	fields := input.GetFields()
+
+	filter := domain.SearchFilter{
+		TweetID: fields["tweet_id"].GetStringValue(),
+		UserID:  fields["user_id"].GetStringValue(),
+	}
+
+	results, nextCursor, err := c.service.SearchComments(
+		ctx,
+		fields["query"].GetStringValue(),
+		filter,
+		fields["cursor"].GetStringValue(),
+		int(fields["limit"].GetNumberValue()),
+	)
+
+	if err != nil {
+		c.log.Errorf("SearchComments: %v", err.Error())
+		return nil, grpc_errors.NewStatus(err, "SearchComments")
+	}
+
+	items := make([]interface{}, 0, len(results))
+
+	for _, result := range results {
+		item := commentToMap(result.Comment)
+		item["rank"] = float64(result.Rank)
+		item["snippet"] = result.Snippet
+
+		items = append(items, item)
+	}
+
+	return structpb.NewStruct(map[string]interface{}{
+		"results": items,
+		"cursor":  nextCursor,
+	})
+}
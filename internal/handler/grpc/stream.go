@@ -0,0 +1,85 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type CommentStreamServer interface {
+	SubscribeTweetComments(input *structpb.Struct, stream grpc.ServerStream) error
+}
+
+// CommentStreamServiceDesc describes the real-time comment feed.
+//
+// SubscribeTweetComments is server-streaming: the client sends a Struct with tweet_id and an optional
+// cursor and receives a Struct per event with type (created, updated or deleted), cursor and comment.
+// Reconnecting with the cursor of the last received event resumes the feed without gaps. A cursor that
+// is too old fails with OutOfRange, the client then reloads the comments with GetAllTweetComments.
+var CommentStreamServiceDesc = grpc.ServiceDesc{
+	ServiceName: "comments.CommentStream",
+	HandlerType: (*CommentStreamServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "SubscribeTweetComments",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				in := new(structpb.Struct)
+				if err := stream.RecvMsg(in); err != nil {
+					return err
+				}
+				return srv.(CommentStreamServer).SubscribeTweetComments(in, stream)
+			},
+			ServerStreams: true,
+		},
+	},
+}
+
+func (c *CommentGRPC) SubscribeTweetComments(input *structpb.Struct, stream grpc.ServerStream) error {
+	ctx, span := c.tracer.Start(stream.Context(), "SubscribeTweetComments")
+	defer span.End()
+
+	if err := validateSubscribeTweetComments(input); err != nil {
+		return grpc_errors.NewStatus(err, "SubscribeTweetComments")
+	}
+
+	fields := input.GetFields()
+
+	err := c.service.SubscribeTweetComments(
+		ctx,
+		fields["tweet_id"].GetStringValue(),
+		fields["cursor"].GetStringValue(),
+		func(event domain.CommentEvent) error {
+			msg, err := commentEventToStruct(event)
+
+			if err != nil {
+				return err
+			}
+
+			return stream.SendMsg(msg)
+		},
+	)
+
+	// the client going away is the normal end of a subscription
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+
+	if err != nil {
+		c.log.Errorf("SubscribeTweetComments: %v", err.Error())
+		return grpc_errors.NewStatus(err, "SubscribeTweetComments")
+	}
+
+	return nil
+}
+
+func commentEventToStruct(event domain.CommentEvent) (*structpb.Struct, error) {
+	return structpb.NewStruct(map[string]interface{}{
+		"type":    event.Type,
+		"cursor":  event.Cursor,
+		"comment": commentToMap(event.Comment),
+	})
+}
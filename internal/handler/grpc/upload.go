@@ -0,0 +1,142 @@
+package grpc
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"time"
+)
+
+const (
+	uploadUserIDHeader   = "user-id"
+	uploadNameHeader     = "image-name"
+	uploadChecksumHeader = "image-sha256"
+)
+
+type ImageUploadServer interface {
+	UploadImage(stream grpc.ServerStream) error
+	CreateUploadURL(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error)
+}
+
+// ImageUploadServiceDesc describes the image upload operations.
+//
+// UploadImage is client-streaming: the client sends image chunks as BytesValue messages and
+// receives an upload token as a StringValue. The owner, original file name and optional
+// SHA-256 checksum are passed in the request metadata.
+//
+// CreateUploadURL takes a Struct with user_id, name, content_type and size and returns a Struct
+// with token, url and expires_at. The client PUTs the image to url directly.
+var ImageUploadServiceDesc = grpc.ServiceDesc{
+	ServiceName: "comments.ImageUpload",
+	HandlerType: (*ImageUploadServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateUploadURL",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(ImageUploadServer).CreateUploadURL(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{
+					Server:     srv,
+					FullMethod: "/comments.ImageUpload/CreateUploadURL",
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(ImageUploadServer).CreateUploadURL(ctx, req.(*structpb.Struct))
+				}
+				return interceptor(ctx, in, info, handler)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "UploadImage",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				return srv.(ImageUploadServer).UploadImage(stream)
+			},
+			ClientStreams: true,
+		},
+	},
+}
+
+func (c *CommentGRPC) UploadImage(stream grpc.ServerStream) error {
+	ctx, span := c.tracer.Start(stream.Context(), "UploadImage")
+	defer span.End()
+
+	if err := validateUploadImage(ctx); err != nil {
+		return grpc_errors.NewStatus(err, "UploadImage")
+	}
+
+	token, err := c.service.UploadImage(
+		ctx,
+		metadataValue(ctx, uploadUserIDHeader),
+		metadataValue(ctx, uploadNameHeader),
+		metadataValue(ctx, uploadChecksumHeader),
+		&chunkReader{stream: stream},
+	)
+
+	if err != nil {
+		c.log.Errorf("UploadImage: %v", err.Error())
+		return grpc_errors.NewStatus(err, "UploadImage")
+	}
+
+	return stream.SendMsg(wrapperspb.String(token))
+}
+
+func (c *CommentGRPC) CreateUploadURL(ctx context.Context, input *structpb.Struct) (*structpb.Struct, error) {
+	ctx, span := c.tracer.Start(ctx, "CreateUploadURL")
+	defer span.End()
+
+	if err := validateCreateUploadURL(input); err != nil {
+		return nil, grpc_errors.NewStatus(err, "CreateUploadURL")
+	}
+
+	fields := input.GetFields()
+
+	upload, err := c.service.CreateUploadURL(
+		ctx,
+		fields["user_id"].GetStringValue(),
+		fields["name"].GetStringValue(),
+		fields["content_type"].GetStringValue(),
+		int64(fields["size"].GetNumberValue()),
+	)
+
+	if err != nil {
+		c.log.Errorf("CreateUploadURL: %v", err.Error())
+		return nil, grpc_errors.NewStatus(err, "CreateUploadURL")
+	}
+
+	return structpb.NewStruct(map[string]interface{}{
+		"token":      upload.Token,
+		"url":        upload.URL,
+		"expires_at": upload.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+// chunkReader exposes the chunks received from the client stream as a contiguous reader.
+type chunkReader struct {
+	stream grpc.ServerStream
+	chunk  []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		var msg wrapperspb.BytesValue
+
+		if err := r.stream.RecvMsg(&msg); err != nil {
+			return 0, err
+		}
+
+		r.chunk = msg.GetValue()
+	}
+
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+
+	return n, nil
+}
@@ -0,0 +1,295 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"github.com/Verce11o/yata-comments/internal/lib/pagination"
+	pb "github.com/Verce11o/yata-protos/gen/go/comments"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/structpb"
+	"math"
+	"strings"
+)
+
+// validator collects every violation of a request, so the client can fix all fields at once.
+type validator struct {
+	violations []grpc_errors.FieldViolation
+}
+
+func (v *validator) add(field string, format string, args ...interface{}) {
+	v.violations = append(v.violations, grpc_errors.FieldViolation{Field: field, Description: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(field string, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+		return false
+	}
+
+	return true
+}
+
+func (v *validator) uuid(field string, value string) {
+	if v.required(field, value) {
+		v.optionalUUID(field, value)
+	}
+}
+
+func (v *validator) optionalUUID(field string, value string) {
+	if value == "" {
+		return
+	}
+
+	if _, err := uuid.Parse(value); err != nil {
+		v.add(field, "must be a UUID, got %q", value)
+	}
+}
+
+// count checks an optional Struct number that is used as a non-negative integer.
+func (v *validator) count(field string, value *structpb.Value) {
+	if value == nil {
+		return
+	}
+
+	if _, ok := value.GetKind().(*structpb.Value_NumberValue); !ok {
+		v.add(field, "must be a number")
+		return
+	}
+
+	if number := value.GetNumberValue(); number < 0 || number != math.Trunc(number) {
+		v.add(field, "must be a non-negative integer, got %v", number)
+	}
+}
+
+// stringField checks that an optional Struct value, when present, is a string.
+func (v *validator) stringField(fields map[string]*structpb.Value, field string) {
+	value, ok := fields[field]
+
+	if !ok {
+		return
+	}
+
+	if _, ok := value.GetKind().(*structpb.Value_StringValue); !ok {
+		v.add(field, "must be a string")
+	}
+}
+
+// cursor checks that an optional Struct cursor is a string that decode accepts.
+func (v *validator) cursor(fields map[string]*structpb.Value, field string, decode func(cursor string) error) {
+	value, ok := fields[field]
+
+	if !ok {
+		return
+	}
+
+	if _, ok := value.GetKind().(*structpb.Value_StringValue); !ok {
+		v.add(field, "must be a string")
+		return
+	}
+
+	v.cursorValue(field, value.GetStringValue(), decode)
+}
+
+func (v *validator) cursorValue(field string, value string, decode func(cursor string) error) {
+	if value == "" {
+		return
+	}
+
+	if err := decode(value); err != nil {
+		v.add(field, "must be a cursor returned by a previous page")
+	}
+}
+
+func (v *validator) image(image *pb.Image) {
+	if image == nil {
+		return
+	}
+
+	if len(image.GetChunk()) > 0 && image.GetContentType() == "" {
+		v.add("image.content_type", "is required with image data")
+	}
+}
+
+func (v *validator) attachments(ctx context.Context) {
+	for _, token := range metadataValues(ctx, uploadTokenHeader) {
+		v.uuid(uploadTokenHeader, token)
+	}
+
+	for _, entry := range metadataValues(ctx, attachmentHeader) {
+		if token, ok := strings.CutPrefix(entry, domain.UploadAttachmentPrefix); ok {
+			v.uuid(attachmentHeader, token)
+			continue
+		}
+
+		v.uuid(attachmentHeader, entry)
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+
+	return &grpc_errors.ValidationError{Violations: v.violations}
+}
+
+func validateCreateComment(ctx context.Context, input *pb.CreateCommentRequest) error {
+	v := &validator{}
+
+	v.uuid("tweet_id", input.GetTweetId())
+	v.uuid("user_id", input.GetUserId())
+	v.required("text", input.GetText())
+	v.image(input.GetImage())
+	v.attachments(ctx)
+
+	return v.err()
+}
+
+func validateGetComment(input *pb.GetCommentRequest) error {
+	v := &validator{}
+
+	v.uuid("comment_id", input.GetCommentId())
+
+	return v.err()
+}
+
+func validateGetAllTweetComments(input *pb.GetAllTweetCommentsRequest) error {
+	v := &validator{}
+
+	v.uuid("tweet_id", input.GetTweetId())
+	v.cursorValue("cursor", input.GetCursor(), decodeCursor)
+
+	return v.err()
+}
+
+func validateUpdateComment(ctx context.Context, input *pb.UpdateCommentRequest) error {
+	v := &validator{}
+
+	v.uuid("comment_id", input.GetCommentId())
+	v.uuid("user_id", input.GetUserId())
+	v.required("text", input.GetText())
+	v.image(input.GetImage())
+	v.attachments(ctx)
+
+	return v.err()
+}
+
+func validateDeleteComment(input *pb.DeleteCommentRequest) error {
+	v := &validator{}
+
+	v.uuid("comment_id", input.GetCommentId())
+	v.uuid("user_id", input.GetUserId())
+
+	return v.err()
+}
+
+func validateUploadImage(ctx context.Context) error {
+	v := &validator{}
+
+	v.uuid(uploadUserIDHeader, metadataValue(ctx, uploadUserIDHeader))
+
+	return v.err()
+}
+
+func validateCreateUploadURL(input *structpb.Struct) error {
+	v := &validator{}
+	fields := input.GetFields()
+
+	v.uuid("user_id", fields["user_id"].GetStringValue())
+	v.required("content_type", fields["content_type"].GetStringValue())
+	v.stringField(fields, "name")
+
+	if size, ok := fields["size"]; ok {
+		v.count("size", size)
+	} else {
+		v.add("size", "is required")
+	}
+
+	return v.err()
+}
+
+func validateBlockImage(input *structpb.Struct) error {
+	v := &validator{}
+	fields := input.GetFields()
+
+	v.uuid("attachment_id", fields["attachment_id"].GetStringValue())
+	v.uuid("moderator_id", fields["moderator_id"].GetStringValue())
+	v.stringField(fields, "reason")
+
+	return v.err()
+}
+
+func validateUnblockImage(input *structpb.Struct) error {
+	v := &validator{}
+
+	v.uuid("attachment_id", input.GetFields()["attachment_id"].GetStringValue())
+
+	return v.err()
+}
+
+func validateSubscribeTweetComments(input *structpb.Struct) error {
+	v := &validator{}
+	fields := input.GetFields()
+
+	v.uuid("tweet_id", fields["tweet_id"].GetStringValue())
+	v.cursor(fields, "cursor", decodeEventCursor)
+
+	return v.err()
+}
+
+func validateListChanges(input *structpb.Struct) error {
+	v := &validator{}
+	fields := input.GetFields()
+
+	v.optionalUUID("tweet_id", fields["tweet_id"].GetStringValue())
+	v.optionalUUID("user_id", fields["user_id"].GetStringValue())
+	v.cursor(fields, "token", decodeChangeToken)
+	v.count("limit", fields["limit"])
+
+	return v.err()
+}
+
+func validateSearchComments(input *structpb.Struct) error {
+	v := &validator{}
+	fields := input.GetFields()
+
+	v.required("query", fields["query"].GetStringValue())
+	v.optionalUUID("tweet_id", fields["tweet_id"].GetStringValue())
+	v.optionalUUID("user_id", fields["user_id"].GetStringValue())
+	v.cursor(fields, "cursor", decodeRankCursor)
+	v.count("limit", fields["limit"])
+
+	return v.err()
+}
+
+func validateListHashtagComments(input *structpb.Struct) error {
+	v := &validator{}
+	fields := input.GetFields()
+
+	v.required("tag", fields["tag"].GetStringValue())
+	v.cursor(fields, "cursor", decodeCursor)
+
+	return v.err()
+}
+
+func decodeCursor(cursor string) error {
+	_, _, err := pagination.DecodeCursor(cursor)
+	return err
+}
+
+func decodeRankCursor(cursor string) error {
+	_, _, err := pagination.DecodeRankCursor(cursor)
+	return err
+}
+
+func decodeChangeToken(token string) error {
+	_, _, err := pagination.DecodeChangeToken(token)
+	return err
+}
+
+func decodeEventCursor(cursor string) error {
+	_, _, err := pagination.DecodeEventCursor(cursor)
+	return err
+}
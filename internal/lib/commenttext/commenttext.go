@@ -0,0 +1,94 @@
+package commenttext
+
+import (
+	"fmt"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	zeroWidthNonJoiner = '\u200c'
+	zeroWidthJoiner    = '\u200d'
+)
+
+// invisible are format characters that carry no meaning in a comment but can hide or reorder text:
+// zero-width spaces, word joiners, byte order marks and bidirectional overrides.
+var invisible = map[rune]struct{}{
+	'\u00ad': {}, '\u180e': {}, '\u200b': {}, '\u200e': {}, '\u200f': {},
+	'\u202a': {}, '\u202b': {}, '\u202c': {}, '\u202d': {}, '\u202e': {},
+	'\u2060': {}, '\u2061': {}, '\u2062': {}, '\u2063': {}, '\u2064': {},
+	'\u2066': {}, '\u2067': {}, '\u2068': {}, '\u2069': {}, '\ufeff': {},
+}
+
+type Validator struct {
+	cfg config.Comments
+}
+
+func NewValidator(cfg config.Comments) *Validator {
+	return &Validator{cfg: cfg}
+}
+
+// Normalize cleans comment text and checks it against the configured limits. The text is converted to NFC,
+// line breaks to "\n", invisible and control characters other than newlines and tabs are dropped and
+// surrounding whitespace is trimmed. The length limit counts grapheme clusters, so an emoji or a letter
+// with combining marks counts as one character.
+func (v *Validator) Normalize(text string) (string, error) {
+	if !utf8.ValidString(text) {
+		return "", fmt.Errorf("%w: text is not valid UTF-8", grpc_errors.ErrInvalidText)
+	}
+
+	text = strings.TrimSpace(norm.NFC.String(strip(text)))
+
+	if text == "" {
+		return "", fmt.Errorf("%w: text is empty", grpc_errors.ErrInvalidText)
+	}
+
+	if length := uniseg.GraphemeClusterCount(text); length > v.cfg.MaxTextLength {
+		return "", fmt.Errorf("%w: text has %d characters, limit is %d", grpc_errors.ErrInvalidText, length, v.cfg.MaxTextLength)
+	}
+
+	// combining marks can stack without bound inside a single grapheme cluster
+	if size := len(text); size > v.cfg.MaxTextBytes {
+		return "", fmt.Errorf("%w: text has %d bytes, limit is %d", grpc_errors.ErrInvalidText, size, v.cfg.MaxTextBytes)
+	}
+
+	return text, nil
+}
+
+func strip(text string) string {
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text)
+	runes := []rune(text)
+
+	var b strings.Builder
+	b.Grow(len(text))
+
+	for i, r := range runes {
+		if _, ok := invisible[r]; ok {
+			continue
+		}
+
+		// joiners are kept between visible characters, where they form emoji sequences and ligatures
+		if r == zeroWidthJoiner || r == zeroWidthNonJoiner {
+			if i == 0 || i == len(runes)-1 || !joinable(runes[i-1]) || !joinable(runes[i+1]) {
+				continue
+			}
+		}
+
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func joinable(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsControl(r) && r != zeroWidthJoiner && r != zeroWidthNonJoiner
+}
@@ -0,0 +1,79 @@
+package commenttext
+
+import (
+	"errors"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	v := NewValidator(config.Comments{MaxTextLength: 5, MaxTextBytes: 64})
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "hello", "hello"},
+		{"trims surrounding whitespace", " \n\thello \r\n", "hello"},
+		{"composes to NFC", "cafe\u0301", "caf\u00e9"},
+		{"normalizes line breaks", "a\r\nb\rc", "a\nb\nc"},
+		{"keeps tabs and newlines", "a\tb\nc", "a\tb\nc"},
+		{"drops zero-width space and BOM", "\ufeffhe\u200bllo", "hello"},
+		{"drops bidirectional overrides", "ab\u202ecd\u202c", "abcd"},
+		{"drops control characters", "he\x00l\x07lo", "hello"},
+		{"keeps joiner inside an emoji sequence", "👩\u200d💻", "👩\u200d💻"},
+		{"drops joiner at the edges", "\u200dhi\u200d", "hi"},
+		{"drops joiner next to a space", "a \u200cb", "a b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Normalize(tt.text)
+			if err != nil {
+				t.Fatalf("Normalize(%q): %v", tt.text, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLimits(t *testing.T) {
+	v := NewValidator(config.Comments{MaxTextLength: 3, MaxTextBytes: 32})
+
+	tests := []struct {
+		name  string
+		text  string
+		valid bool
+	}{
+		{"at the limit", "abc", true},
+		{"over the limit", "abcd", false},
+		{"combining characters at the limit", "a\u0301e\u0301q\u0301", true},
+		{"combining characters over the limit", "a\u0301e\u0301o\u0301u\u0301", false},
+		{"emoji sequences count once", "👩\u200d💻👍🏽🇩🇪", true},
+		{"invisible characters do not count", "a\u200bb\u200bc\u200b", true},
+		{"stacked combining marks over the byte limit", "a" + strings.Repeat("\u0301", 20), false},
+		{"empty", "", false},
+		{"only invisible characters", "\u200b\ufeff \u202e", false},
+		{"invalid UTF-8", "ab\xff", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Normalize(tt.text)
+
+			if tt.valid && err != nil {
+				t.Fatalf("Normalize(%q): %v", tt.text, err)
+			}
+
+			if !tt.valid && !errors.Is(err, grpc_errors.ErrInvalidText) {
+				t.Fatalf("Normalize(%q) error = %v, want %v", tt.text, err, grpc_errors.ErrInvalidText)
+			}
+		})
+	}
+}
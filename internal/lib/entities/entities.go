@@ -0,0 +1,178 @@
+package entities
+
+import (
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxHashtagLength = 100
+
+var (
+	// hashtagPattern requires a letter or underscore in the tag, so "#1" is not a hashtag,
+	// and skips tags glued to a preceding word, HTML entities like "&#39;" and "##".
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])([#＃]([\p{L}\p{N}_]*[\p{L}_][\p{L}\p{N}_]*))`)
+	urlPattern     = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+)
+
+// Parse returns the hashtags and URLs of text ordered by position, hashtags inside URLs are skipped.
+func Parse(text string) []domain.Entity {
+	urls := ParseURLs(text)
+	result := append([]domain.Entity(nil), urls...)
+
+	for _, hashtag := range ParseHashtags(text) {
+		if !overlaps(hashtag, urls) {
+			result = append(result, hashtag)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ByteStart < result[j].ByteStart
+	})
+
+	return result
+}
+
+// ParseHashtags returns the hashtags of text, Value is the lower-cased tag without the hash sign.
+func ParseHashtags(text string) []domain.Entity {
+	var hashtags []domain.Entity
+
+	for _, match := range hashtagPattern.FindAllStringSubmatchIndex(text, -1) {
+		tag := text[match[4]:match[5]]
+
+		if utf8.RuneCountInString(tag) > maxHashtagLength {
+			continue
+		}
+
+		hashtags = append(hashtags, newEntity(text, domain.EntityHashtag, match[2], match[3], strings.ToLower(tag)))
+	}
+
+	return hashtags
+}
+
+// ParseURLs returns the links of text, Value is the URL with a scheme added to bare "www." links.
+func ParseURLs(text string) []domain.Entity {
+	var urls []domain.Entity
+
+	for _, match := range urlPattern.FindAllStringIndex(text, -1) {
+		start, end := match[0], trimURL(text[match[0]:match[1]])+match[0]
+		value := text[start:end]
+
+		if strings.HasPrefix(strings.ToLower(value), "www.") {
+			value = "http://" + value
+		}
+
+		urls = append(urls, newEntity(text, domain.EntityURL, start, end, value))
+	}
+
+	return urls
+}
+
+// Hashtags returns the distinct tags of text.
+func Hashtags(text string) []string {
+	var tags []string
+	seen := make(map[string]struct{})
+
+	for _, entity := range Parse(text) {
+		if entity.Type != domain.EntityHashtag {
+			continue
+		}
+
+		if _, ok := seen[entity.Value]; !ok {
+			seen[entity.Value] = struct{}{}
+			tags = append(tags, entity.Value)
+		}
+	}
+
+	return tags
+}
+
+// NormalizeHashtag turns user input like "#Go" into the stored form of the tag.
+func NormalizeHashtag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(tag), "#"), "＃")
+
+	return strings.ToLower(tag)
+}
+
+// trimURL drops trailing punctuation that ends the sentence rather than the link and returns the new length.
+// A closing parenthesis is kept when the link itself opened one, as in Wikipedia links.
+func trimURL(url string) int {
+	for len(url) > 0 {
+		last := url[len(url)-1]
+
+		if last == ')' && strings.Count(url, "(") >= strings.Count(url, ")") {
+			break
+		}
+
+		if !strings.ContainsRune(".,;:!?)]}'", rune(last)) {
+			break
+		}
+
+		url = url[:len(url)-1]
+	}
+
+	return len(url)
+}
+
+func newEntity(text string, entityType string, byteStart int, byteEnd int, value string) domain.Entity {
+	start := utf8.RuneCountInString(text[:byteStart])
+
+	return domain.Entity{
+		Type:      entityType,
+		Text:      text[byteStart:byteEnd],
+		Value:     value,
+		Start:     start,
+		End:       start + utf8.RuneCountInString(text[byteStart:byteEnd]),
+		ByteStart: byteStart,
+		ByteEnd:   byteEnd,
+	}
+}
+
+func overlaps(entity domain.Entity, others []domain.Entity) bool {
+	for _, other := range others {
+		if entity.ByteStart < other.ByteEnd && other.ByteStart < entity.ByteEnd {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ForComment returns every entity of a comment: hashtags and URLs parsed from text and its resolved mentions.
+func ForComment(text string, mentions []domain.Mention) []domain.Entity {
+	result := Parse(text)
+
+	for _, mention := range mentions {
+		byteStart, byteEnd := byteOffset(text, mention.Start), byteOffset(text, mention.End)
+
+		result = append(result, domain.Entity{
+			Type:      domain.EntityMention,
+			Text:      text[byteStart:byteEnd],
+			Value:     mention.UserID.String(),
+			Start:     mention.Start,
+			End:       mention.End,
+			ByteStart: byteStart,
+			ByteEnd:   byteEnd,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ByteStart < result[j].ByteStart
+	})
+
+	return result
+}
+
+// byteOffset converts a rune offset into a byte offset, offsets past the end are clamped to it.
+func byteOffset(text string, runeOffset int) int {
+	for i := range text {
+		if runeOffset == 0 {
+			return i
+		}
+		runeOffset--
+	}
+
+	return len(text)
+}
@@ -0,0 +1,171 @@
+package entities
+
+import (
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/google/uuid"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []domain.Entity
+	}{
+		{
+			name: "ascii",
+			text: "hi #Go see https://go.dev.",
+			want: []domain.Entity{
+				{Type: domain.EntityHashtag, Text: "#Go", Value: "go", Start: 3, End: 6, ByteStart: 3, ByteEnd: 6},
+				{Type: domain.EntityURL, Text: "https://go.dev", Value: "https://go.dev", Start: 11, End: 25, ByteStart: 11, ByteEnd: 25},
+			},
+		},
+		{
+			name: "multi-byte text before the entity",
+			text: "привет 👋 #тест",
+			want: []domain.Entity{
+				{Type: domain.EntityHashtag, Text: "#тест", Value: "тест", Start: 9, End: 14, ByteStart: 18, ByteEnd: 27},
+			},
+		},
+		{
+			name: "full-width hash sign",
+			text: "é＃Tag",
+			want: nil,
+		},
+		{
+			name: "full-width hash sign after space",
+			text: "é ＃Tag",
+			want: []domain.Entity{
+				{Type: domain.EntityHashtag, Text: "＃Tag", Value: "tag", Start: 2, End: 6, ByteStart: 3, ByteEnd: 9},
+			},
+		},
+		{
+			name: "bare www link gets a scheme",
+			text: "(see www.example.com/a_(b))",
+			want: []domain.Entity{
+				{Type: domain.EntityURL, Text: "www.example.com/a_(b)", Value: "http://www.example.com/a_(b)", Start: 5, End: 26, ByteStart: 5, ByteEnd: 26},
+			},
+		},
+		{
+			name: "hashtag inside a link is skipped",
+			text: "https://example.com/#section",
+			want: []domain.Entity{
+				{Type: domain.EntityURL, Text: "https://example.com/#section", Value: "https://example.com/#section", Start: 0, End: 28, ByteStart: 0, ByteEnd: 28},
+			},
+		},
+		{
+			name: "not hashtags",
+			text: "#1 a#b &#39; ##x",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Mention
+	}{
+		{
+			name: "ascii",
+			text: "@Alice hi @bob",
+			want: []Mention{{Username: "alice", Start: 0, End: 6}, {Username: "bob", Start: 10, End: 14}},
+		},
+		{
+			name: "multi-byte text before the mention",
+			text: "日本語 🎉 @alice",
+			want: []Mention{{Username: "alice", Start: 6, End: 12}},
+		},
+		{
+			name: "combining mark before the mention",
+			text: "cafe\u0301 @bob",
+			want: []Mention{{Username: "bob", Start: 6, End: 10}},
+		},
+		{
+			name: "e-mail address",
+			text: "mail a@b.com or @@bob",
+			want: nil,
+		},
+		{
+			name: "inside a link",
+			text: "https://example.com/@alice",
+			want: nil,
+		},
+		{
+			name: "longer than the username limit",
+			text: "@" + "a123456789b123456789c123456789d12",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseMentions(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMentions(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForCommentConvertsMentionOffsets(t *testing.T) {
+	userID := uuid.New()
+	text := "日本語 🎉 @alice #go"
+
+	got := ForComment(text, []domain.Mention{{UserID: userID, Username: "alice", Start: 6, End: 12}})
+
+	want := []domain.Entity{
+		{Type: domain.EntityMention, Text: "@alice", Value: userID.String(), Start: 6, End: 12, ByteStart: 15, ByteEnd: 21},
+		{Type: domain.EntityHashtag, Text: "#go", Value: "go", Start: 13, End: 16, ByteStart: 22, ByteEnd: 25},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ForComment(%q) = %+v, want %+v", text, got, want)
+	}
+
+	for _, entity := range got {
+		if text[entity.ByteStart:entity.ByteEnd] != entity.Text {
+			t.Errorf("bytes %d:%d of the text are %q, want %q", entity.ByteStart, entity.ByteEnd, text[entity.ByteStart:entity.ByteEnd], entity.Text)
+		}
+	}
+}
+
+func TestByteOffset(t *testing.T) {
+	text := "aé🎉b"
+
+	tests := []struct {
+		runeOffset int
+		want       int
+	}{
+		{0, 0},
+		{1, 1},
+		{2, 3},
+		{3, 7},
+		{4, 8},
+		{10, 8},
+	}
+
+	for _, tt := range tests {
+		if got := byteOffset(text, tt.runeOffset); got != tt.want {
+			t.Errorf("byteOffset(%q, %d) = %d, want %d", text, tt.runeOffset, got, tt.want)
+		}
+	}
+}
+
+func TestHashtagsAreDistinct(t *testing.T) {
+	got := Hashtags("#Go #go #GO #rust")
+	want := []string{"go", "rust"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Hashtags = %v, want %v", got, want)
+	}
+}
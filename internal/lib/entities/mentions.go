@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// mentionPattern matches @username not preceded by a word character or another @, so e-mail addresses are skipped.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])(@(\w{1,32}))`)
+
+// Mention is an @username in comment text. Start and End are rune offsets of the whole "@username", End is exclusive.
+type Mention struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// ParseMentions returns the mentions of text in order of appearance, @words inside links are skipped.
+func ParseMentions(text string) []Mention {
+	var mentions []Mention
+
+	urls := ParseURLs(text)
+
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[2], match[3]
+
+		if overlaps(domain.Entity{ByteStart: start, ByteEnd: end}, urls) {
+			continue
+		}
+
+		// a mention directly followed by more username-like characters was cut at the length limit
+		if next, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && (isWordRune(next) || next == '@') {
+			continue
+		}
+
+		runeStart := utf8.RuneCountInString(text[:start])
+
+		mentions = append(mentions, Mention{
+			Username: strings.ToLower(text[match[4]:match[5]]),
+			Start:    runeStart,
+			End:      runeStart + utf8.RuneCountInString(text[start:end]),
+		})
+	}
+
+	return mentions
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
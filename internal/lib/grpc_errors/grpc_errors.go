@@ -4,35 +4,189 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
 )
 
+// Domain is sent in ErrorInfo details, reasons are unique within it.
+const Domain = "comments.yata"
+
 var (
-	ErrAddMinio         = errors.New("add file error")
-	ErrNotFound         = errors.New("not found")
-	ErrPermissionDenied = errors.New("PermissionDenied")
-	ErrInvalidCursor    = errors.New("invalid pagination cursor")
+	ErrAddMinio            = errors.New("add file error")
+	ErrNotFound            = errors.New("not found")
+	ErrPermissionDenied    = errors.New("PermissionDenied")
+	ErrInvalidCursor       = errors.New("invalid pagination cursor")
+	ErrIdempotencyKey      = errors.New("idempotency key reused with a different payload")
+	ErrIdempotencyConflict = errors.New("idempotency key already used")
+	ErrInvalidImage        = errors.New("invalid image")
+	ErrInfectedImage       = errors.New("image rejected by malware scan")
+	ErrScannerUnavailable  = errors.New("malware scanner unavailable")
+	ErrEventsUnavailable   = errors.New("comment events subscription lost")
+	ErrBlockedImage        = errors.New("image is blocked")
+	ErrCursorExpired       = errors.New("cursor is older than the retained events")
+	ErrInvalidSearchQuery  = errors.New("invalid search query")
+	ErrInvalidHashtag      = errors.New("invalid hashtag")
+	ErrInvalidText         = errors.New("invalid comment text")
+	ErrInvalidArgument     = errors.New("invalid argument")
 )
 
+type errorKind struct {
+	err    error
+	code   codes.Code
+	reason string
+	// field is reported as a BadRequest violation for errors about a single request field
+	field string
+}
+
+// errorKinds is matched in order, the first kind the error wraps wins.
+var errorKinds = []errorKind{
+	{err: sql.ErrNoRows, code: codes.NotFound, reason: "NOT_FOUND"},
+	{err: context.Canceled, code: codes.Canceled, reason: "CANCELED"},
+	{err: context.DeadlineExceeded, code: codes.DeadlineExceeded, reason: "DEADLINE_EXCEEDED"},
+	{err: ErrAddMinio, code: codes.Internal, reason: "STORAGE_WRITE_FAILED"},
+	{err: ErrNotFound, code: codes.NotFound, reason: "NOT_FOUND"},
+	{err: ErrPermissionDenied, code: codes.PermissionDenied, reason: "PERMISSION_DENIED"},
+	{err: ErrInvalidArgument, code: codes.InvalidArgument, reason: "INVALID_ARGUMENT"},
+	{err: ErrInvalidCursor, code: codes.InvalidArgument, reason: "INVALID_CURSOR", field: "cursor"},
+	{err: ErrIdempotencyKey, code: codes.FailedPrecondition, reason: "IDEMPOTENCY_KEY_REUSED"},
+	{err: ErrIdempotencyConflict, code: codes.Aborted, reason: "IDEMPOTENCY_CONFLICT"},
+	{err: ErrInvalidImage, code: codes.InvalidArgument, reason: "INVALID_IMAGE"},
+	{err: ErrInvalidText, code: codes.InvalidArgument, reason: "INVALID_TEXT", field: "text"},
+	{err: ErrInvalidHashtag, code: codes.InvalidArgument, reason: "INVALID_HASHTAG", field: "tag"},
+	{err: ErrInvalidSearchQuery, code: codes.InvalidArgument, reason: "INVALID_SEARCH_QUERY", field: "query"},
+	{err: ErrCursorExpired, code: codes.OutOfRange, reason: "CURSOR_EXPIRED", field: "cursor"},
+	{err: ErrInfectedImage, code: codes.InvalidArgument, reason: "INFECTED_IMAGE"},
+	{err: ErrBlockedImage, code: codes.InvalidArgument, reason: "BLOCKED_IMAGE"},
+	{err: ErrScannerUnavailable, code: codes.Unavailable, reason: "SCANNER_UNAVAILABLE"},
+	{err: ErrEventsUnavailable, code: codes.Unavailable, reason: "EVENTS_UNAVAILABLE"},
+	{err: redis.Nil, code: codes.NotFound, reason: "NOT_FOUND"},
+}
+
+var internalKind = errorKind{code: codes.Internal, reason: "INTERNAL"}
+
+// FieldViolation describes a single invalid request field.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ValidationError lists every invalid field of a request, it wraps ErrInvalidArgument.
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+
+	for _, violation := range e.Violations {
+		descriptions = append(descriptions, violation.Field+": "+violation.Description)
+	}
+
+	return fmt.Sprintf("%v: %s", ErrInvalidArgument, strings.Join(descriptions, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidArgument
+}
+
+// domainErrorKinds maps the kinds repositories classify infrastructure errors into.
+var domainErrorKinds = map[domain.ErrorKind]errorKind{
+	domain.KindInternal:    internalKind,
+	domain.KindNotFound:    {code: codes.NotFound, reason: "NOT_FOUND"},
+	domain.KindInvalid:     {code: codes.InvalidArgument, reason: "INVALID_ARGUMENT"},
+	domain.KindConflict:    {code: codes.AlreadyExists, reason: "CONFLICT"},
+	domain.KindAborted:     {code: codes.Aborted, reason: "ABORTED"},
+	domain.KindUnavailable: {code: codes.Unavailable, reason: "UNAVAILABLE"},
+}
+
+func parseErrorKind(err error) errorKind {
+	kind := internalKind
+
+	for _, sentinel := range errorKinds {
+		if errors.Is(err, sentinel.err) {
+			kind = sentinel
+			break
+		}
+	}
+
+	var domainErr *domain.Error
+
+	if !errors.As(err, &domainErr) {
+		return kind
+	}
+
+	// the repository classification wins over the driver error it wraps,
+	// a sentinel with the same code keeps its more specific reason
+	if classified := domainErrorKinds[domainErr.Kind]; classified.code != kind.code {
+		return classified
+	}
+
+	return kind
+}
+
 func ParseGRPCErrStatusCode(err error) codes.Code {
-	switch {
-	case errors.Is(err, sql.ErrNoRows):
-		return codes.NotFound
-	case errors.Is(err, context.Canceled):
-		return codes.Canceled
-	case errors.Is(err, context.DeadlineExceeded):
-		return codes.DeadlineExceeded
-	case errors.Is(err, ErrAddMinio):
-		return codes.Internal
-	case errors.Is(err, ErrNotFound):
-		return codes.NotFound
-	case errors.Is(err, ErrPermissionDenied):
-		return codes.PermissionDenied
-	case errors.Is(err, ErrInvalidCursor):
-		return codes.InvalidArgument
-	case errors.Is(err, redis.Nil):
-		return codes.NotFound
-	}
-	return codes.Internal
+	return parseErrorKind(err).code
+}
+
+// ParseGRPCErrReason returns the ErrorInfo reason of err, INTERNAL for errors without a sentinel.
+func ParseGRPCErrReason(err error) string {
+	return parseErrorKind(err).reason
+}
+
+// NewStatus converts err to a gRPC status error prefixed with the method name. The status carries an
+// ErrorInfo with the reason, whether a retry may succeed and, for invalid arguments, a BadRequest with the offending fields.
+func NewStatus(err error, method string) error {
+	kind := parseErrorKind(err)
+	st := status.Newf(kind.code, "%s: %v", method, err)
+
+	errorInfo := &errdetails.ErrorInfo{Reason: kind.reason, Domain: Domain}
+
+	if domain.IsRetryable(err) || kind.code == codes.Unavailable {
+		errorInfo.Metadata = map[string]string{"retryable": "true"}
+	}
+
+	var withDetails *status.Status
+	var detailsErr error
+
+	if badRequest := newBadRequest(err, kind); badRequest != nil {
+		withDetails, detailsErr = st.WithDetails(errorInfo, badRequest)
+	} else {
+		withDetails, detailsErr = st.WithDetails(errorInfo)
+	}
+
+	if detailsErr != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
+
+func newBadRequest(err error, kind errorKind) *errdetails.BadRequest {
+	var validationErr *ValidationError
+
+	if errors.As(err, &validationErr) {
+		badRequest := &errdetails.BadRequest{}
+
+		for _, violation := range validationErr.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+
+		return badRequest
+	}
+
+	if kind.field == "" {
+		return nil
+	}
+
+	return &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: kind.field, Description: err.Error()}},
+	}
 }
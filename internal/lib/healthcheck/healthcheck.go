@@ -0,0 +1,121 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"github.com/Verce11o/yata-comments/config"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"time"
+)
+
+// Check probes a single dependency, its status is published under Name.
+// Only required checks take the services passed to NewChecker out of service when they fail.
+type Check struct {
+	Name     string
+	Required bool
+	Ping     func(ctx context.Context) error
+}
+
+// Checker updates the standard gRPC health service from periodic dependency checks.
+type Checker struct {
+	log      *zap.SugaredLogger
+	server   *health.Server
+	cfg      config.Health
+	services []string
+	checks   []Check
+}
+
+// NewChecker reports services as serving while every required check passes, each check is also
+// reported as its own service. Everything is NOT_SERVING until the first round of checks.
+func NewChecker(log *zap.SugaredLogger, server *health.Server, cfg config.Health, services []string, checks ...Check) (*Checker, error) {
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("health check interval must be positive, got %s", cfg.Interval)
+	}
+
+	if cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("health check timeout must be positive, got %s", cfg.Timeout)
+	}
+
+	for _, service := range services {
+		server.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+
+	for _, check := range checks {
+		server.SetServingStatus(check.Name, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+
+	return &Checker{log: log, server: server, cfg: cfg, services: services, checks: checks}, nil
+}
+
+// Run checks the dependencies every interval until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.checkAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown reports everything as NOT_SERVING and ignores later check results, then waits the
+// configured drain delay so load balancers notice before the server starts draining connections.
+func (c *Checker) Shutdown() {
+	c.server.Shutdown()
+
+	time.Sleep(c.cfg.DrainDelay)
+}
+
+func (c *Checker) checkAll(ctx context.Context) {
+	healthy := true
+
+	for _, check := range c.checks {
+		status := healthpb.HealthCheckResponse_SERVING
+
+		if err := c.ping(ctx, check); err != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			healthy = healthy && !check.Required
+			c.log.Warnf("health check %s failed: %v", check.Name, err)
+		}
+
+		c.server.SetServingStatus(check.Name, status)
+	}
+
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if healthy {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+
+	for _, service := range c.services {
+		c.server.SetServingStatus(service, status)
+	}
+}
+
+func (c *Checker) ping(ctx context.Context, check Check) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	return check.Ping(ctx)
+}
+
+// DialCheck pings a TCP dependency by opening a connection to address.
+func DialCheck(address string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+}
@@ -0,0 +1,24 @@
+package images
+
+import "github.com/Verce11o/yata-comments/internal/domain"
+
+const (
+	BlocklistReject = "reject"
+	BlocklistFlag   = "flag"
+)
+
+// MatchBlocklist returns the first entry within the configured Hamming distance of hash, or nil.
+func (p *Processor) MatchBlocklist(hash uint64, entries []domain.BlocklistEntry) *domain.BlocklistEntry {
+	for i := range entries {
+		if HammingDistance(hash, uint64(entries[i].PerceptualHash)) <= p.cfg.Blocklist.Threshold {
+			return &entries[i]
+		}
+	}
+
+	return nil
+}
+
+// FlagBlocklisted reports whether matching images are accepted and flagged instead of rejected.
+func (p *Processor) FlagBlocklisted() bool {
+	return p.cfg.Blocklist.Action == BlocklistFlag
+}
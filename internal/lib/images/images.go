@@ -0,0 +1,36 @@
+package images
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+)
+
+const (
+	OriginalNameMetadata = "Original-Name"
+	SignatureMetadata    = "Malware-Signature"
+	UploadPrefix         = "uploads/"
+)
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ObjectName generates a storage key for a new image scoped by its owner,
+// so client-supplied file names never collide or overwrite each other.
+func ObjectName(userID string, contentType string) string {
+	return fmt.Sprintf("%s/%s%s", userID, uuid.NewString(), extensions[strings.ToLower(contentType)])
+}
+
+// UploadName generates a temporary storage key for an image uploaded ahead of the comment referencing it.
+func UploadName(userID string, token string) string {
+	return fmt.Sprintf("%s%s/%s", UploadPrefix, userID, token)
+}
+
+// IsLegacyName reports whether the image was stored under a client-supplied name.
+func IsLegacyName(name string) bool {
+	return name != "" && !strings.Contains(name, "/")
+}
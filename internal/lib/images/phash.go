@@ -0,0 +1,81 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"golang.org/x/image/draw"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+const (
+	phashSize     = 32
+	phashLowFreqs = 8
+)
+
+// phashCosines holds the DCT basis for the low frequencies kept in the hash.
+var phashCosines = func() [phashLowFreqs][phashSize]float64 {
+	var cosines [phashLowFreqs][phashSize]float64
+
+	for u := 0; u < phashLowFreqs; u++ {
+		for x := 0; x < phashSize; x++ {
+			cosines[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * phashSize))
+		}
+	}
+
+	return cosines
+}()
+
+// PerceptualHash computes a 64-bit DCT hash of the image. Re-encoded, resized or slightly edited copies
+// of the same picture produce hashes within a small Hamming distance of each other.
+func PerceptualHash(data []byte) (uint64, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		return 0, fmt.Errorf("%w: cannot decode image: %v", grpc_errors.ErrInvalidImage, err)
+	}
+
+	gray := image.NewGray(image.Rect(0, 0, phashSize, phashSize))
+	draw.CatmullRom.Scale(gray, gray.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	var coefficients [phashLowFreqs * phashLowFreqs]float64
+
+	for u := 0; u < phashLowFreqs; u++ {
+		for v := 0; v < phashLowFreqs; v++ {
+			var sum float64
+
+			for x := 0; x < phashSize; x++ {
+				for y := 0; y < phashSize; y++ {
+					sum += float64(gray.GrayAt(x, y).Y) * phashCosines[u][x] * phashCosines[v][y]
+				}
+			}
+
+			coefficients[u*phashLowFreqs+v] = sum
+		}
+	}
+
+	// the DC coefficient only carries the average brightness and is left out of the median
+	sorted := make([]float64, len(coefficients)-1)
+	copy(sorted, coefficients[1:])
+	sort.Float64s(sorted)
+
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+
+	for i, coefficient := range coefficients {
+		if coefficient > median {
+			hash |= 1 << uint(i)
+		}
+	}
+
+	return hash, nil
+}
+
+// HammingDistance counts the bits that differ between two perceptual hashes.
+func HammingDistance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,118 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	_ "golang.org/x/image/webp"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"unicode/utf8"
+)
+
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+	// PerceptualHash is computed by Process for matching against the image blocklist.
+	PerceptualHash uint64
+}
+
+type Processor struct {
+	cfg          config.Images
+	allowedTypes map[string]struct{}
+}
+
+func NewProcessor(cfg config.Images) *Processor {
+	allowedTypes := make(map[string]struct{}, len(cfg.AllowedTypes))
+
+	for _, contentType := range cfg.AllowedTypes {
+		allowedTypes[contentType] = struct{}{}
+	}
+
+	return &Processor{cfg: cfg, allowedTypes: allowedTypes}
+}
+
+func (p *Processor) MaxSize() int64 {
+	return p.cfg.MaxSize
+}
+
+func (p *Processor) MaxAttachments() int {
+	return p.cfg.MaxAttachments
+}
+
+func (p *Processor) ValidateAltTexts(altTexts []string) error {
+	for _, altText := range altTexts {
+		if length := utf8.RuneCountInString(altText); length > p.cfg.MaxAltTextLength {
+			return fmt.Errorf("%w: alt text has %d characters, limit is %d", grpc_errors.ErrInvalidImage, length, p.cfg.MaxAltTextLength)
+		}
+	}
+
+	return nil
+}
+
+// ValidateDeclared checks the type and size announced by a client before it uploads the image itself.
+func (p *Processor) ValidateDeclared(contentType string, size int64) error {
+	if _, ok := p.allowedTypes[contentType]; !ok {
+		return fmt.Errorf("%w: image type %s is not allowed", grpc_errors.ErrInvalidImage, contentType)
+	}
+
+	if size <= 0 || size > p.cfg.MaxSize {
+		return fmt.Errorf("%w: image size %d must be between 1 and %d bytes", grpc_errors.ErrInvalidImage, size, p.cfg.MaxSize)
+	}
+
+	return nil
+}
+
+// Process validates uploaded bytes against the configured limits, strips embedded metadata and computes the perceptual hash.
+// The content type is sniffed from the data itself, the one claimed by the client is ignored.
+func (p *Processor) Process(data []byte) (*Image, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: image is empty", grpc_errors.ErrInvalidImage)
+	}
+
+	if int64(len(data)) > p.cfg.MaxSize {
+		return nil, fmt.Errorf("%w: image size %d exceeds limit of %d bytes", grpc_errors.ErrInvalidImage, len(data), p.cfg.MaxSize)
+	}
+
+	contentType := http.DetectContentType(data)
+
+	if _, ok := p.allowedTypes[contentType]; !ok {
+		return nil, fmt.Errorf("%w: image type %s is not allowed", grpc_errors.ErrInvalidImage, contentType)
+	}
+
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot decode %s image: %v", grpc_errors.ErrInvalidImage, contentType, err)
+	}
+
+	if imageConfig.Width > p.cfg.MaxWidth || imageConfig.Height > p.cfg.MaxHeight {
+		return nil, fmt.Errorf("%w: image dimensions %dx%d exceed limit of %dx%d", grpc_errors.ErrInvalidImage,
+			imageConfig.Width, imageConfig.Height, p.cfg.MaxWidth, p.cfg.MaxHeight)
+	}
+
+	sanitized, err := p.sanitize(&Image{
+		Data:        data,
+		ContentType: contentType,
+		Width:       imageConfig.Width,
+		Height:      imageConfig.Height,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sanitized.PerceptualHash, err = PerceptualHash(sanitized.Data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sanitized, nil
+}
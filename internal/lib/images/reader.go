@@ -0,0 +1,49 @@
+package images
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"hash"
+	"io"
+)
+
+// CappedReader fails as soon as more than limit bytes were read and
+// computes a SHA-256 checksum of everything that passed through it.
+type CappedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+	hash  hash.Hash
+}
+
+func NewCappedReader(r io.Reader, limit int64) *CappedReader {
+	return &CappedReader{r: r, limit: limit, hash: sha256.New()}
+}
+
+func (c *CappedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+
+	if c.read > c.limit {
+		return 0, fmt.Errorf("%w: image size exceeds limit of %d bytes", grpc_errors.ErrInvalidImage, c.limit)
+	}
+
+	c.hash.Write(p[:n])
+
+	return n, err
+}
+
+func (c *CappedReader) Size() int64 {
+	return c.read
+}
+
+func (c *CappedReader) Checksum() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
+
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,249 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	UnsanitizableReject = "reject"
+	UnsanitizablePass   = "pass"
+
+	exifOrientationTag = 0x0112
+
+	// VP8X flags announcing the EXIF and XMP chunks of an extended WebP file
+	webpExifFlag = 0x08
+	webpXMPFlag  = 0x04
+)
+
+// sanitize re-encodes the image so that EXIF, location and other embedded metadata is dropped.
+// JPEG orientation is applied to the pixels before the metadata carrying it is removed.
+func (p *Processor) sanitize(img *Image) (*Image, error) {
+	var buf bytes.Buffer
+
+	switch img.ContentType {
+	case "image/jpeg":
+		src, err := jpeg.Decode(bytes.NewReader(img.Data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot decode image/jpeg image: %v", grpc_errors.ErrInvalidImage, err)
+		}
+
+		src = orient(src, jpegOrientation(img.Data))
+
+		if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+
+		return &Image{Data: buf.Bytes(), ContentType: img.ContentType, Width: src.Bounds().Dx(), Height: src.Bounds().Dy()}, nil
+	case "image/png":
+		src, err := png.Decode(bytes.NewReader(img.Data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot decode image/png image: %v", grpc_errors.ErrInvalidImage, err)
+		}
+
+		if err := png.Encode(&buf, src); err != nil {
+			return nil, err
+		}
+
+		return &Image{Data: buf.Bytes(), ContentType: img.ContentType, Width: img.Width, Height: img.Height}, nil
+	case "image/gif":
+		src, err := gif.DecodeAll(bytes.NewReader(img.Data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot decode image/gif image: %v", grpc_errors.ErrInvalidImage, err)
+		}
+
+		if err := gif.EncodeAll(&buf, src); err != nil {
+			return nil, err
+		}
+
+		return &Image{Data: buf.Bytes(), ContentType: img.ContentType, Width: img.Width, Height: img.Height}, nil
+	case "image/webp":
+		// there is no WebP encoder, the metadata chunks are cut out of the container instead
+		data, err := stripWebPMetadata(img.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot parse image/webp image: %v", grpc_errors.ErrInvalidImage, err)
+		}
+
+		return &Image{Data: data, ContentType: img.ContentType, Width: img.Width, Height: img.Height}, nil
+	}
+
+	if p.cfg.Unsanitizable == UnsanitizablePass {
+		return img, nil
+	}
+
+	return nil, fmt.Errorf("%w: metadata cannot be removed from %s images", grpc_errors.ErrInvalidImage, img.ContentType)
+}
+
+// stripWebPMetadata removes the EXIF and XMP chunks of a WebP file and clears their VP8X flags.
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errors.New("not a RIFF WEBP container")
+	}
+
+	riffEnd := 8 + int(binary.LittleEndian.Uint32(data[4:8]))
+	if riffEnd > len(data) {
+		return nil, errors.New("truncated RIFF container")
+	}
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+
+	for i := 12; i < riffEnd; {
+		if i+8 > riffEnd {
+			return nil, errors.New("truncated chunk header")
+		}
+
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		end := i + 8 + size + size%2 // chunks are padded to an even size
+
+		if end > riffEnd {
+			return nil, fmt.Errorf("truncated %q chunk", fourCC)
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if size > 0 {
+				chunk[8] &^= webpExifFlag | webpXMPFlag
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+
+		i = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+
+	return out, nil
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG image, 1 if it has none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // image data starts, no more metadata segments
+			return 1
+		}
+
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+
+		if marker == 0xE1 {
+			if orientation := exifOrientation(data[i+4 : i+2+size]); orientation != 0 {
+				return orientation
+			}
+		}
+
+		i += 2 + size
+	}
+
+	return 1
+}
+
+func exifOrientation(segment []byte) int {
+	if len(segment) < 14 || string(segment[:6]) != "Exif\x00\x00" {
+		return 0
+	}
+
+	tiff := segment[6:]
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 0
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+
+		if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+			return orientation
+		}
+		return 0
+	}
+
+	return 0
+}
+
+// orient transforms the image so that it is displayed upright without the EXIF orientation tag.
+func orient(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+
+			switch orientation {
+			case 2: // mirror horizontal
+				dx, dy = width-1-x, y
+			case 3: // rotate 180
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirror vertical
+				dx, dy = x, height-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90 clockwise
+				dx, dy = height-1-y, x
+			case 7: // transverse
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotate 90 counterclockwise
+				dx, dy = y, width-1-x
+			}
+
+			dst.Set(dx, dy, src.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,82 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"testing"
+)
+
+func webpChunk(fourCC string, payload []byte) []byte {
+	chunk := make([]byte, 8, 8+len(payload)+1)
+	copy(chunk, fourCC)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(payload)))
+	chunk = append(chunk, payload...)
+
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+
+	return chunk
+}
+
+func webpFile(chunks ...[]byte) []byte {
+	body := []byte("WEBP")
+
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+
+	data := make([]byte, 8, 8+len(body))
+	copy(data, "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(body)))
+
+	return append(data, body...)
+}
+
+func TestSanitizeWebPRemovesMetadata(t *testing.T) {
+	vp8x := make([]byte, 10)
+	vp8x[0] = webpExifFlag | webpXMPFlag | 0x20 // ICC profile flag is kept
+	bitstream := []byte{0x2f, 0x01, 0x02}
+
+	data := webpFile(
+		webpChunk("VP8X", vp8x),
+		webpChunk("VP8L", bitstream),
+		webpChunk("EXIF", []byte("Exif\x00\x00GPS location")),
+		webpChunk("XMP ", []byte("<x:xmpmeta/>")),
+	)
+
+	p := NewProcessor(config.Images{Unsanitizable: UnsanitizableReject})
+
+	sanitized, err := p.sanitize(&Image{Data: data, ContentType: "image/webp", Width: 1, Height: 1})
+	if err != nil {
+		t.Fatalf("sanitize: %v", err)
+	}
+
+	if bytes.Contains(sanitized.Data, []byte("EXIF")) || bytes.Contains(sanitized.Data, []byte("GPS location")) {
+		t.Error("EXIF chunk is still present")
+	}
+
+	if bytes.Contains(sanitized.Data, []byte("XMP ")) {
+		t.Error("XMP chunk is still present")
+	}
+
+	want := webpFile(webpChunk("VP8X", append([]byte{0x20}, vp8x[1:]...)), webpChunk("VP8L", bitstream))
+
+	if !bytes.Equal(sanitized.Data, want) {
+		t.Errorf("sanitized file = %x, want %x", sanitized.Data, want)
+	}
+}
+
+func TestSanitizeWebPRejectsTruncatedFile(t *testing.T) {
+	data := webpFile(webpChunk("VP8L", []byte{0x2f, 0x01}))
+	data = data[:len(data)-1]
+
+	p := NewProcessor(config.Images{Unsanitizable: UnsanitizableReject})
+
+	if _, err := p.sanitize(&Image{Data: data, ContentType: "image/webp"}); !errors.Is(err, grpc_errors.ErrInvalidImage) {
+		t.Errorf("sanitize error = %v, want %v", err, grpc_errors.ErrInvalidImage)
+	}
+}
@@ -0,0 +1,144 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/image/draw"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strings"
+)
+
+const jpegQuality = 85
+
+type Variant struct {
+	Name string
+	*Image
+}
+
+// Variants produces a resized copy of the image for every configured variant.
+// Images are only scaled down, aspect ratio is preserved.
+func (p *Processor) Variants(img *Image) ([]*Variant, error) {
+	if len(p.cfg.Variants) == 0 {
+		return nil, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(img.Data))
+
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make([]*Variant, 0, len(p.cfg.Variants))
+
+	for _, cfg := range p.cfg.Variants {
+		width, height := fit(src.Bounds().Dx(), src.Bounds().Dy(), cfg.MaxWidth, cfg.MaxHeight)
+
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+		data, contentType, err := encode(dst, img.ContentType)
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode %s variant: %w", cfg.Name, err)
+		}
+
+		variants = append(variants, &Variant{
+			Name:  cfg.Name,
+			Image: &Image{Data: data, ContentType: contentType, Width: width, Height: height},
+		})
+	}
+
+	return variants, nil
+}
+
+// VariantNames returns the object names of all configured variants of the given image.
+func (p *Processor) VariantNames(objectName string) map[string]string {
+	names := make(map[string]string, len(p.cfg.Variants))
+
+	for _, cfg := range p.cfg.Variants {
+		names[cfg.Name] = VariantName(objectName, cfg.Name)
+	}
+
+	return names
+}
+
+// VariantName derives the object name of a variant stored beside the original image.
+func VariantName(objectName string, variant string) string {
+	ext := path.Ext(objectName)
+
+	return fmt.Sprintf("%s_%s%s", strings.TrimSuffix(objectName, ext), variant, variantExtension(ext))
+}
+
+// SourceNames returns the names of the original images the given object may belong to,
+// the object itself included. Variants are matched by their configured name suffix.
+func (p *Processor) SourceNames(objectName string) []string {
+	names := []string{objectName}
+
+	ext := path.Ext(objectName)
+	stem := strings.TrimSuffix(objectName, ext)
+
+	for _, cfg := range p.cfg.Variants {
+		source, ok := strings.CutSuffix(stem, "_"+cfg.Name)
+		if !ok {
+			continue
+		}
+
+		for _, originalExt := range extensions {
+			if variantExtension(originalExt) == ext {
+				names = append(names, source+originalExt)
+			}
+		}
+	}
+
+	return names
+}
+
+// variantExtension names variants without decoding them, WebP variants keep the .jpg name even when
+// transparency makes them PNG, clients rely on the stored content type.
+func variantExtension(originalExt string) string {
+	switch originalExt {
+	case ".png", ".gif":
+		return ".png"
+	}
+	return ".jpg"
+}
+
+// encode keeps transparency for formats that may have it and falls back to JPEG otherwise.
+func encode(img image.Image, originalType string) ([]byte, string, error) {
+	var buf bytes.Buffer
+
+	switch {
+	case originalType == "image/png", originalType == "image/gif", originalType == "image/webp" && !isOpaque(img):
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// isOpaque reports whether every pixel of img is fully opaque.
+func isOpaque(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return opaque.Opaque()
+	}
+
+	return false
+}
+
+func fit(width, height, maxWidth, maxHeight int) (int, int) {
+	if width <= maxWidth && height <= maxHeight {
+		return width, height
+	}
+
+	scale := min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+
+	return max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))
+}
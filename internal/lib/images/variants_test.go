@@ -0,0 +1,51 @@
+package images
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEncodeKeepsTransparency(t *testing.T) {
+	opaque := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	transparent := image.NewRGBA(image.Rect(0, 0, 2, 2))
+
+	for x := 0; x < 2; x++ {
+		for y := 0; y < 2; y++ {
+			opaque.Set(x, y, color.RGBA{R: 255, A: 255})
+			transparent.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	transparent.Set(1, 1, color.RGBA{})
+
+	tests := []struct {
+		name         string
+		img          image.Image
+		originalType string
+		want         string
+	}{
+		{"opaque jpeg", opaque, "image/jpeg", "image/jpeg"},
+		{"opaque png", opaque, "image/png", "image/png"},
+		{"opaque gif", opaque, "image/gif", "image/png"},
+		{"opaque webp", opaque, "image/webp", "image/jpeg"},
+		{"transparent webp", transparent, "image/webp", "image/png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, contentType, err := encode(tt.img, tt.originalType)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			if contentType != tt.want {
+				t.Fatalf("got content type %s, want %s", contentType, tt.want)
+			}
+
+			if len(data) == 0 {
+				t.Fatal("got no data")
+			}
+		})
+	}
+}
@@ -2,33 +2,36 @@ package pagination
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
 	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
 	"github.com/google/uuid"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// DecodeCursor parses a cursor of EncodeCursor, a malformed cursor yields an invalid argument domain error.
 func DecodeCursor(encodedCursor string) (time.Time, uuid.UUID, error) {
 	byt, err := base64.StdEncoding.DecodeString(encodedCursor)
 	if err != nil {
-		return time.Time{}, [16]byte{}, err
+		return time.Time{}, [16]byte{}, invalidCursor(err)
 	}
 
 	arrStr := strings.Split(string(byt), ",")
 	if len(arrStr) != 2 {
-		err = grpc_errors.ErrInvalidCursor
-		return time.Time{}, [16]byte{}, err
+		return time.Time{}, [16]byte{}, invalidCursor(errors.New("unexpected number of fields"))
 	}
 
 	res, err := time.Parse(time.RFC3339Nano, arrStr[0])
 	if err != nil {
-		return time.Time{}, [16]byte{}, err
+		return time.Time{}, [16]byte{}, invalidCursor(err)
 	}
 
 	tweetID, err := uuid.Parse(arrStr[1])
 	if err != nil {
-		return time.Time{}, [16]byte{}, err
+		return time.Time{}, [16]byte{}, invalidCursor(err)
 	}
 
 	return res, tweetID, nil
@@ -38,3 +41,93 @@ func EncodeCursor(t time.Time, uuid string) string {
 	key := fmt.Sprintf("%s,%s", t.Format(time.RFC3339Nano), uuid)
 	return base64.StdEncoding.EncodeToString([]byte(key))
 }
+
+// DecodeChangeToken parses a token of EncodeChangeToken, the position of the last change a client has seen.
+func DecodeChangeToken(encodedToken string) (uint64, int64, error) {
+	byt, err := base64.StdEncoding.DecodeString(encodedToken)
+	if err != nil {
+		return 0, 0, invalidCursor(err)
+	}
+
+	arrStr := strings.Split(string(byt), ",")
+	if len(arrStr) != 2 {
+		return 0, 0, invalidCursor(errors.New("unexpected number of fields"))
+	}
+
+	xactID, err := strconv.ParseUint(arrStr[0], 10, 64)
+	if err != nil {
+		return 0, 0, invalidCursor(err)
+	}
+
+	changeID, err := strconv.ParseInt(arrStr[1], 10, 64)
+	if err != nil {
+		return 0, 0, invalidCursor(err)
+	}
+
+	if changeID < 0 {
+		return 0, 0, invalidCursor(errors.New("negative change id"))
+	}
+
+	return xactID, changeID, nil
+}
+
+func EncodeChangeToken(xactID uint64, changeID int64) string {
+	key := fmt.Sprintf("%d,%d", xactID, changeID)
+	return base64.StdEncoding.EncodeToString([]byte(key))
+}
+
+func DecodeRankCursor(encodedCursor string) (float32, uuid.UUID, error) {
+	byt, err := base64.StdEncoding.DecodeString(encodedCursor)
+	if err != nil {
+		return 0, [16]byte{}, invalidCursor(err)
+	}
+
+	arrStr := strings.Split(string(byt), ",")
+	if len(arrStr) != 2 {
+		return 0, [16]byte{}, invalidCursor(errors.New("unexpected number of fields"))
+	}
+
+	rank, err := strconv.ParseFloat(arrStr[0], 32)
+	if err != nil {
+		return 0, [16]byte{}, invalidCursor(err)
+	}
+
+	commentID, err := uuid.Parse(arrStr[1])
+	if err != nil {
+		return 0, [16]byte{}, invalidCursor(err)
+	}
+
+	return float32(rank), commentID, nil
+}
+
+// EncodeRankCursor keeps the rank at float32 precision, so it compares equal to the real computed by Postgres.
+func EncodeRankCursor(rank float32, uuid string) string {
+	key := fmt.Sprintf("%s,%s", strconv.FormatFloat(float64(rank), 'g', -1, 32), uuid)
+	return base64.StdEncoding.EncodeToString([]byte(key))
+}
+
+// DecodeEventCursor parses a comment event cursor, the "<ms>-<seq>" ID of a Redis stream entry.
+func DecodeEventCursor(cursor string) (uint64, uint64, error) {
+	ms, seq, ok := strings.Cut(cursor, "-")
+	if !ok {
+		return 0, 0, invalidCursor(errors.New("missing sequence number"))
+	}
+
+	msValue, err := strconv.ParseUint(ms, 10, 64)
+	if err != nil {
+		return 0, 0, invalidCursor(err)
+	}
+
+	seqValue, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, 0, invalidCursor(err)
+	}
+
+	return msValue, seqValue, nil
+}
+
+// invalidCursor classifies a cursor that cannot be decoded as a client error, so repositories pass it
+// through as an invalid argument whichever part of the cursor is malformed.
+func invalidCursor(cause error) error {
+	return domain.NewError(domain.KindInvalid, "cannot decode cursor", fmt.Errorf("%w: %w", grpc_errors.ErrInvalidCursor, cause))
+}
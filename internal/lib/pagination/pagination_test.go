@@ -0,0 +1,145 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"github.com/google/uuid"
+	"math"
+	"testing"
+	"time"
+)
+
+func encode(raw string) string {
+	return base64.StdEncoding.EncodeToString([]byte(raw))
+}
+
+func checkInvalidCursor(t *testing.T, err error) {
+	t.Helper()
+
+	if !errors.Is(err, grpc_errors.ErrInvalidCursor) {
+		t.Fatalf("error = %v, want %v", err, grpc_errors.ErrInvalidCursor)
+	}
+
+	if kind := domain.ErrorKindOf(err); kind != domain.KindInvalid {
+		t.Fatalf("error kind = %v, want %v", kind, domain.KindInvalid)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	commentID := uuid.New()
+
+	gotTime, gotID, err := DecodeCursor(EncodeCursor(createdAt, commentID.String()))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if !gotTime.Equal(createdAt) || gotID != commentID {
+		t.Errorf("got (%v, %v), want (%v, %v)", gotTime, gotID, createdAt, commentID)
+	}
+}
+
+func TestRankCursorRoundTrip(t *testing.T) {
+	commentID := uuid.New()
+
+	for _, rank := range []float32{0, 0.1, 0.0607927, 1e-20, math.MaxFloat32} {
+		gotRank, gotID, err := DecodeRankCursor(EncodeRankCursor(rank, commentID.String()))
+		if err != nil {
+			t.Fatalf("decode rank %v: %v", rank, err)
+		}
+
+		// the rank must compare equal, not just close, or the next page repeats or skips rows
+		if gotRank != rank || gotID != commentID {
+			t.Errorf("got (%v, %v), want (%v, %v)", gotRank, gotID, rank, commentID)
+		}
+	}
+}
+
+func TestChangeTokenRoundTrip(t *testing.T) {
+	tests := []struct {
+		xactID   uint64
+		changeID int64
+	}{
+		{0, 0},
+		{745, 12},
+		{math.MaxUint64, math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		xactID, changeID, err := DecodeChangeToken(EncodeChangeToken(tt.xactID, tt.changeID))
+		if err != nil {
+			t.Fatalf("decode (%d, %d): %v", tt.xactID, tt.changeID, err)
+		}
+
+		if xactID != tt.xactID || changeID != tt.changeID {
+			t.Errorf("got (%d, %d), want (%d, %d)", xactID, changeID, tt.xactID, tt.changeID)
+		}
+	}
+}
+
+func TestDecodeEventCursor(t *testing.T) {
+	ms, seq, err := DecodeEventCursor("1704067200000-3")
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if ms != 1704067200000 || seq != 3 {
+		t.Errorf("got (%d, %d), want (1704067200000, 3)", ms, seq)
+	}
+}
+
+func TestMalformedCursors(t *testing.T) {
+	commentID := uuid.NewString()
+
+	tests := []struct {
+		name   string
+		decode func(cursor string) error
+		cursor string
+	}{
+		{"cursor not base64", decodeCursor, "not base64!"},
+		{"cursor single field", decodeCursor, encode("2024-01-02T03:04:05Z")},
+		{"cursor extra field", decodeCursor, encode("2024-01-02T03:04:05Z," + commentID + ",1")},
+		{"cursor bad time", decodeCursor, encode("yesterday," + commentID)},
+		{"cursor bad uuid", decodeCursor, encode("2024-01-02T03:04:05Z,42")},
+		{"rank not base64", decodeRankCursor, "%%%"},
+		{"rank bad number", decodeRankCursor, encode("high," + commentID)},
+		{"rank out of range", decodeRankCursor, encode("1e40," + commentID)},
+		{"rank bad uuid", decodeRankCursor, encode("0.5,42")},
+		{"change token not base64", decodeChangeToken, "%%%"},
+		{"change token legacy format", decodeChangeToken, encode("12")},
+		{"change token negative xact", decodeChangeToken, encode("-1,12")},
+		{"change token negative change", decodeChangeToken, encode("745,-12")},
+		{"change token bad number", decodeChangeToken, encode("745,twelve")},
+		{"event cursor no sequence", decodeEventCursor, "1704067200000"},
+		{"event cursor bad time", decodeEventCursor, "now-0"},
+		{"event cursor negative sequence", decodeEventCursor, "1704067200000--1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkInvalidCursor(t, tt.decode(tt.cursor))
+		})
+	}
+}
+
+func decodeCursor(cursor string) error {
+	_, _, err := DecodeCursor(cursor)
+	return err
+}
+
+func decodeRankCursor(cursor string) error {
+	_, _, err := DecodeRankCursor(cursor)
+	return err
+}
+
+func decodeChangeToken(token string) error {
+	_, _, err := DecodeChangeToken(token)
+	return err
+}
+
+func decodeEventCursor(cursor string) error {
+	_, _, err := DecodeEventCursor(cursor)
+	return err
+}
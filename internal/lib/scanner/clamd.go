@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	clamdChunkSize   = 64 << 10
+	clamdInstream    = "zINSTREAM\x00"
+	clamdCleanReply  = "stream: OK"
+	clamdFoundSuffix = " FOUND"
+	clamdReplyPrefix = "stream: "
+)
+
+// Clamd streams files to a clamd daemon over TCP or a unix socket using the INSTREAM command.
+type Clamd struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+func NewClamd(network string, address string, timeout time.Duration) *Clamd {
+	return &Clamd{network: network, address: address, timeout: timeout}
+}
+
+func (c *Clamd) Scan(ctx context.Context, data []byte) (Result, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(c.timeout)
+
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		return Result{}, err
+	}
+
+	if err := c.stream(conn, data); err != nil {
+		return Result{}, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return parseClamdReply(strings.TrimSuffix(reply, "\x00"))
+}
+
+// stream sends data as length-prefixed chunks terminated by an empty chunk.
+func (c *Clamd) stream(conn net.Conn, data []byte) error {
+	if _, err := conn.Write([]byte(clamdInstream)); err != nil {
+		return err
+	}
+
+	for len(data) > 0 {
+		chunk := data[:min(len(data), clamdChunkSize)]
+		data = data[len(chunk):]
+
+		if err := binary.Write(conn, binary.BigEndian, uint32(len(chunk))); err != nil {
+			return err
+		}
+
+		if _, err := conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+
+	return binary.Write(conn, binary.BigEndian, uint32(0))
+}
+
+func parseClamdReply(reply string) (Result, error) {
+	if reply == clamdCleanReply {
+		return Result{}, nil
+	}
+
+	if strings.HasPrefix(reply, clamdReplyPrefix) && strings.HasSuffix(reply, clamdFoundSuffix) {
+		signature := strings.TrimSuffix(strings.TrimPrefix(reply, clamdReplyPrefix), clamdFoundSuffix)
+
+		return Result{Infected: true, Signature: signature}, nil
+	}
+
+	return Result{}, fmt.Errorf("clamd: %s", reply)
+}
@@ -0,0 +1,47 @@
+package scanner
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/lib/images"
+	"sync"
+)
+
+// Memory flags files by checksum, it stands in for a real scanner in tests and local setups.
+type Memory struct {
+	mu         sync.RWMutex
+	signatures map[string]string
+	err        error
+}
+
+func NewMemory() *Memory {
+	return &Memory{signatures: make(map[string]string)}
+}
+
+// Add marks data as infected with the given signature.
+func (m *Memory) Add(data []byte, signature string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.signatures[images.Checksum(data)] = signature
+}
+
+// SetError makes every following scan fail, simulating an unavailable scanner.
+func (m *Memory) SetError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
+
+func (m *Memory) Scan(ctx context.Context, data []byte) (Result, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.err != nil {
+		return Result{}, m.err
+	}
+
+	signature, ok := m.signatures[images.Checksum(data)]
+
+	return Result{Infected: ok, Signature: signature}, nil
+}
@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"go.uber.org/zap"
+)
+
+const (
+	BackendNone  = "none"
+	BackendClamd = "clamd"
+)
+
+// Result is the verdict for a single file, Signature names the detected malware.
+type Result struct {
+	Infected  bool
+	Signature string
+}
+
+type Scanner interface {
+	Scan(ctx context.Context, data []byte) (Result, error)
+}
+
+// NewScanner builds the configured scanner, scanner errors are wrapped with ErrScannerUnavailable
+// unless the fail-open policy lets the file through.
+func NewScanner(log *zap.SugaredLogger, cfg config.Scanner) (Scanner, error) {
+	var scanner Scanner
+
+	switch cfg.Backend {
+	case BackendNone:
+		return NewNoop(), nil
+	case BackendClamd:
+		scanner = NewClamd(cfg.Network, cfg.Address, cfg.Timeout)
+	default:
+		return nil, fmt.Errorf("unknown scanner backend: %s", cfg.Backend)
+	}
+
+	return &policy{log: log, scanner: scanner, failOpen: cfg.FailOpen}, nil
+}
+
+type policy struct {
+	log      *zap.SugaredLogger
+	scanner  Scanner
+	failOpen bool
+}
+
+func (p *policy) Scan(ctx context.Context, data []byte) (Result, error) {
+	result, err := p.scanner.Scan(ctx, data)
+
+	if err == nil {
+		return result, nil
+	}
+
+	if p.failOpen {
+		p.log.Warnf("malware scanner failed, file accepted without scan: %v", err)
+		return Result{}, nil
+	}
+
+	return Result{}, fmt.Errorf("%w: %v", grpc_errors.ErrScannerUnavailable, err)
+}
+
+// Noop accepts every file, it is used when scanning is disabled.
+type Noop struct{}
+
+func NewNoop() *Noop {
+	return &Noop{}
+}
+
+func (n *Noop) Scan(ctx context.Context, data []byte) (Result, error) {
+	return Result{}, nil
+}
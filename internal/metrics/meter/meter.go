@@ -0,0 +1,59 @@
+package meter
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	metricsdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"log"
+)
+
+type OTLPMetrics struct {
+	Exporter metricsdk.Exporter
+	Provider *metricsdk.MeterProvider
+}
+
+func NewOTLPExporter(ctx context.Context) (metricsdk.Exporter, error) {
+	return otlpmetricgrpc.New(ctx, otlpmetricgrpc.WithInsecure())
+}
+
+func NewMeterProvider(exp metricsdk.Exporter, serviceName string) (*metricsdk.MeterProvider, error) {
+	r, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return metricsdk.NewMeterProvider(
+		metricsdk.WithReader(metricsdk.NewPeriodicReader(exp)),
+		metricsdk.WithResource(r),
+	), nil
+}
+
+// InitMeter installs the global meter provider, instruments created with otel.Meter are exported over OTLP
+// to the same collector as traces.
+func InitMeter(serviceName string) *OTLPMetrics {
+	exporter, err := NewOTLPExporter(context.Background())
+	if err != nil {
+		log.Fatalf("initialize meter exporter: %v", err)
+	}
+
+	mp, err := NewMeterProvider(exporter, serviceName)
+	if err != nil {
+		log.Fatalf("initialize meter provider: %v", err)
+	}
+
+	otel.SetMeterProvider(mp)
+
+	return &OTLPMetrics{
+		Exporter: exporter,
+		Provider: mp,
+	}
+}
@@ -0,0 +1,76 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const usersLookupPath = "/users/lookup"
+
+type httpUser struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+// HTTPDirectory asks the user service for the IDs of usernames with
+// GET {baseURL}/users/lookup?username=a&username=b, answered by a JSON array of {user_id, username}.
+type HTTPDirectory struct {
+	client  *http.Client
+	baseURL string
+	tracer  trace.Tracer
+}
+
+func NewHTTPDirectory(baseURL string, timeout time.Duration, tracer trace.Tracer) *HTTPDirectory {
+	return &HTTPDirectory{client: &http.Client{Timeout: timeout}, baseURL: strings.TrimSuffix(baseURL, "/"), tracer: tracer}
+}
+
+func (d *HTTPDirectory) LookupUsernames(ctx context.Context, usernames []string) (map[string]uuid.UUID, error) {
+	ctx, span := d.tracer.Start(ctx, "httpDirectory.LookupUsernames")
+	defer span.End()
+
+	query := url.Values{"username": usernames}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+usersLookupPath+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, domain.UnavailableError("user directory", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		kind := domain.KindInternal
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			kind = domain.KindUnavailable
+		}
+		return nil, domain.NewError(kind, fmt.Sprintf("user directory returned status %d", resp.StatusCode), nil)
+	}
+
+	var users []httpUser
+
+	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]uuid.UUID, len(users))
+
+	for _, user := range users {
+		result[strings.ToLower(user.Username)] = user.UserID
+	}
+
+	return result, nil
+}
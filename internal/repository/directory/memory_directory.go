@@ -0,0 +1,52 @@
+package directory
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/google/uuid"
+	"strings"
+	"sync"
+)
+
+// MemoryDirectory keeps users in memory, it stands in for the user service in tests and local setups.
+type MemoryDirectory struct {
+	mu    sync.RWMutex
+	users map[string]uuid.UUID
+}
+
+func NewMemoryDirectory(users []config.DirectoryUser) (*MemoryDirectory, error) {
+	d := &MemoryDirectory{users: make(map[string]uuid.UUID, len(users))}
+
+	for _, user := range users {
+		userID, err := uuid.Parse(user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		d.Add(user.Username, userID)
+	}
+
+	return d, nil
+}
+
+func (d *MemoryDirectory) Add(username string, userID uuid.UUID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.users[strings.ToLower(username)] = userID
+}
+
+func (d *MemoryDirectory) LookupUsernames(ctx context.Context, usernames []string) (map[string]uuid.UUID, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make(map[string]uuid.UUID, len(usernames))
+
+	for _, username := range usernames {
+		if userID, ok := d.users[strings.ToLower(username)]; ok {
+			result[strings.ToLower(username)] = userID
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,354 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"github.com/Verce11o/yata-comments/internal/lib/images"
+	pb "github.com/Verce11o/yata-protos/gen/go/comments"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	objectsDir      = "objects"
+	metadataDir     = "metadata"
+	quarantineDir   = "quarantine"
+	tempFilePattern = ".upload-*"
+	imageExpireTime = time.Hour * 24
+
+	FilesPath = "/files/"
+)
+
+type fileMetadata struct {
+	ContentType  string            `json:"content_type"`
+	UserMetadata map[string]string `json:"user_metadata"`
+}
+
+// CommentFilesystem stores images on the local disk with the same object-key semantics as MinIO.
+// Presigned URLs are signed with an HMAC and served by Handler.
+type CommentFilesystem struct {
+	root    string
+	baseURL string
+	secret  []byte
+	tracer  trace.Tracer
+}
+
+func NewCommentFilesystem(root string, baseURL string, secret []byte, tracer trace.Tracer) *CommentFilesystem {
+	return &CommentFilesystem{root: root, baseURL: strings.TrimSuffix(baseURL, "/"), secret: secret, tracer: tracer}
+}
+
+func (f *CommentFilesystem) AddCommentImage(ctx context.Context, image *pb.Image, fileName string) error {
+	ctx, span := f.tracer.Start(ctx, "commentFilesystem.AddImage")
+	defer span.End()
+
+	return f.writeFile(fileName, bytes.NewReader(image.GetChunk()), fileMetadata{
+		ContentType:  image.GetContentType(),
+		UserMetadata: map[string]string{images.OriginalNameMetadata: url.QueryEscape(image.GetName())},
+	})
+}
+
+func (f *CommentFilesystem) AddFile(ctx context.Context, fileName string, reader io.Reader) error {
+	ctx, span := f.tracer.Start(ctx, "commentFilesystem.AddFile")
+	defer span.End()
+
+	return f.writeFile(fileName, reader, fileMetadata{})
+}
+
+func (f *CommentFilesystem) GetFile(ctx context.Context, fileName string, limit int64) ([]byte, error) {
+	ctx, span := f.tracer.Start(ctx, "commentFilesystem.GetFile")
+	defer span.End()
+
+	objectPath, err := f.path(objectsDir, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(objectPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, domain.NotFoundError("filesystem: object not found", grpc_errors.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(io.LimitReader(file, limit+1))
+}
+
+func (f *CommentFilesystem) UpdateCommentImage(ctx context.Context, oldName string, newName string, image *pb.Image) error {
+	ctx, span := f.tracer.Start(ctx, "commentFilesystem.UpdateCommentImage")
+	defer span.End()
+
+	// the new image is stored first so a failed write leaves the old one in place
+	if err := f.AddCommentImage(ctx, image, newName); err != nil {
+		return err
+	}
+
+	return f.DeleteFile(ctx, oldName)
+}
+
+func (f *CommentFilesystem) GetFileURL(ctx context.Context, fileName string) (string, error) {
+	ctx, span := f.tracer.Start(ctx, "commentFilesystem.GetFileURL")
+	defer span.End()
+
+	return f.presign("GET", fileName, imageExpireTime, "", 0)
+}
+
+func (f *CommentFilesystem) GetUploadURL(ctx context.Context, fileName string, contentType string, size int64, expires time.Duration) (string, error) {
+	ctx, span := f.tracer.Start(ctx, "commentFilesystem.GetUploadURL")
+	defer span.End()
+
+	return f.presign("PUT", fileName, expires, contentType, size)
+}
+
+func (f *CommentFilesystem) CopyFile(ctx context.Context, srcName string, dstName string) error {
+	ctx, span := f.tracer.Start(ctx, "commentFilesystem.CopyFile")
+	defer span.End()
+
+	srcPath, err := f.path(objectsDir, srcName)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(srcPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return domain.NotFoundError("filesystem: object not found", grpc_errors.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	metadata, err := f.readMetadata(srcName)
+	if err != nil {
+		return err
+	}
+
+	metadata.UserMetadata = map[string]string{images.OriginalNameMetadata: url.QueryEscape(srcName)}
+
+	return f.writeFile(dstName, src, metadata)
+}
+
+// ListFiles calls fn for every stored object in lexical order, listing stops at the first error.
+func (f *CommentFilesystem) ListFiles(ctx context.Context, fn func(file domain.StoredFile) error) error {
+	ctx, span := f.tracer.Start(ctx, "commentFilesystem.ListFiles")
+	defer span.End()
+
+	objectsRoot := filepath.Join(f.root, objectsDir)
+
+	err := filepath.WalkDir(objectsRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(objectsRoot, path)
+		if err != nil {
+			return err
+		}
+
+		return fn(domain.StoredFile{Name: filepath.ToSlash(rel), Size: info.Size(), LastModified: info.ModTime()})
+	})
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// Ping checks that the storage root is an accessible directory.
+func (f *CommentFilesystem) Ping(ctx context.Context) error {
+	_, span := f.tracer.Start(ctx, "commentFilesystem.Ping")
+	defer span.End()
+
+	info, err := os.Stat(f.root)
+	if err != nil {
+		return domain.UnavailableError("filesystem", err)
+	}
+
+	if !info.IsDir() {
+		return domain.UnavailableError(fmt.Sprintf("filesystem: %s is not a directory", f.root), nil)
+	}
+
+	return nil
+}
+
+func (f *CommentFilesystem) DeleteFile(ctx context.Context, fileName string) error {
+	ctx, span := f.tracer.Start(ctx, "commentFilesystem.DeleteFile")
+	defer span.End()
+
+	for _, dir := range []string{objectsDir, metadataDir} {
+		path, err := f.path(dir, fileName)
+		if err != nil {
+			return err
+		}
+
+		// like S3, removing a missing object is not an error
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QuarantineFile keeps an infected file outside the served objects directory.
+func (f *CommentFilesystem) QuarantineFile(ctx context.Context, fileName string, data []byte, signature string) error {
+	ctx, span := f.tracer.Start(ctx, "commentFilesystem.QuarantineFile")
+	defer span.End()
+
+	quarantinePath, err := f.path(quarantineDir, fileName)
+	if err != nil {
+		return err
+	}
+
+	if err := writeAtomic(quarantinePath, bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	metadataBytes, err := json.Marshal(fileMetadata{UserMetadata: map[string]string{images.SignatureMetadata: signature}})
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(quarantinePath+".json", bytes.NewReader(metadataBytes))
+}
+
+// writeFile stores the object through a temporary file, readers never observe partial writes.
+func (f *CommentFilesystem) writeFile(fileName string, reader io.Reader, metadata fileMetadata) error {
+	objectPath, err := f.path(objectsDir, fileName)
+	if err != nil {
+		return err
+	}
+
+	if err := writeAtomic(objectPath, reader); err != nil {
+		return err
+	}
+
+	metadataBytes, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	metadataPath, err := f.path(metadataDir, fileName)
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(metadataPath, bytes.NewReader(metadataBytes))
+}
+
+func (f *CommentFilesystem) readMetadata(fileName string) (fileMetadata, error) {
+	var metadata fileMetadata
+
+	metadataPath, err := f.path(metadataDir, fileName)
+	if err != nil {
+		return metadata, err
+	}
+
+	metadataBytes, err := os.ReadFile(metadataPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return metadata, nil
+	}
+	if err != nil {
+		return metadata, err
+	}
+
+	err = json.Unmarshal(metadataBytes, &metadata)
+
+	return metadata, err
+}
+
+// path maps an object key to a location below root, keys escaping it are rejected.
+func (f *CommentFilesystem) path(dir string, fileName string) (string, error) {
+	name := filepath.FromSlash(fileName)
+
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid object name %q", fileName)
+	}
+
+	return filepath.Join(f.root, dir, name), nil
+}
+
+func (f *CommentFilesystem) presign(method string, fileName string, expires time.Duration, contentType string, size int64) (string, error) {
+	if _, err := f.path(objectsDir, fileName); err != nil {
+		return "", err
+	}
+
+	expiresAt := time.Now().Add(expires).Unix()
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt, 10))
+	query.Set("signature", f.sign(method, fileName, expiresAt, contentType, size))
+
+	return fmt.Sprintf("%s%s%s?%s", f.baseURL, FilesPath, escapePath(fileName), query.Encode()), nil
+}
+
+// escapePath escapes every segment of an object name, legacy names may contain ?, # or spaces.
+func escapePath(fileName string) string {
+	segments := strings.Split(fileName, "/")
+
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func (f *CommentFilesystem) sign(method string, fileName string, expiresAt int64, contentType string, size int64) string {
+	mac := hmac.New(sha256.New, f.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%d\n%s\n%d", method, fileName, expiresAt, contentType, size)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func writeAtomic(path string, reader io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempFilePattern)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
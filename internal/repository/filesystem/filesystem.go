@@ -0,0 +1,28 @@
+package filesystem
+
+import (
+	"crypto/rand"
+	"github.com/Verce11o/yata-comments/config"
+	"log"
+	"os"
+)
+
+func NewFilesystem(cfg *config.Config) (string, []byte) {
+	root := cfg.Storage.Filesystem.Root
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		log.Fatalf("error while create storage root: %v", err)
+	}
+
+	secret := []byte(cfg.Storage.Filesystem.Secret)
+
+	if len(secret) == 0 { // presigned URLs stop working after a restart, enough for local development
+		secret = make([]byte, 32)
+
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("error while generate storage secret: %v", err)
+		}
+	}
+
+	return root, secret
+}
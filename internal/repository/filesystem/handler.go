@@ -0,0 +1,98 @@
+package filesystem
+
+import (
+	"crypto/hmac"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handler serves presigned GET and PUT requests, the local equivalent of MinIO presigned URLs.
+func (f *CommentFilesystem) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(FilesPath, f.serveFile)
+
+	return mux
+}
+
+func (f *CommentFilesystem) serveFile(w http.ResponseWriter, r *http.Request) {
+	// URL.Path is unescaped, the signature covers the object name as stored
+	fileName := strings.TrimPrefix(r.URL.Path, FilesPath)
+
+	expiresAt, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		http.Error(w, "url expired", http.StatusForbidden)
+		return
+	}
+
+	// a PUT is only accepted with exactly the type and size it was signed for
+	var contentType string
+	var size int64
+
+	if r.Method == http.MethodPut {
+		contentType = r.Header.Get("Content-Type")
+		size = r.ContentLength
+	}
+
+	signature := f.sign(r.Method, fileName, expiresAt, contentType, size)
+
+	if !hmac.Equal([]byte(signature), []byte(r.URL.Query().Get("signature"))) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		f.getFile(w, r, fileName)
+	case http.MethodPut:
+		if err := f.writeFile(fileName, io.LimitReader(r.Body, size), fileMetadata{ContentType: contentType}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *CommentFilesystem) getFile(w http.ResponseWriter, r *http.Request, fileName string) {
+	objectPath, err := f.path(objectsDir, fileName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, err := os.Open(objectPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	metadata, err := f.readMetadata(fileName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if metadata.ContentType != "" {
+		w.Header().Set("Content-Type", metadata.ContentType)
+	}
+
+	http.ServeContent(w, r, fileName, info.ModTime(), file)
+}
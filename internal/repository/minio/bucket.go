@@ -0,0 +1,74 @@
+package minio
+
+import (
+	"context"
+	"fmt"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/lib/images"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+)
+
+const (
+	bucketAlreadyOwnedByYou = "BucketAlreadyOwnedByYou"
+
+	lifecycleEnabled       = "Enabled"
+	expireUploadsRuleID    = "expire-uploads"
+	transitionImagesRuleID = "transition-images"
+)
+
+// EnsureBucket creates the bucket when it is missing and brings its policy and lifecycle rules to the configured state,
+// so a fresh environment works without manual setup.
+func EnsureBucket(ctx context.Context, client *minio.Client, region string, bucket config.Bucket) error {
+	exists, err := client.BucketExists(ctx, bucket.Name)
+	if err != nil {
+		return fmt.Errorf("check bucket %s: %w", bucket.Name, err)
+	}
+
+	if !exists {
+		err = client.MakeBucket(ctx, bucket.Name, minio.MakeBucketOptions{Region: region})
+
+		// another instance may have created the bucket in the meantime
+		if err != nil && minio.ToErrorResponse(err).Code != bucketAlreadyOwnedByYou {
+			return fmt.Errorf("create bucket %s: %w", bucket.Name, err)
+		}
+	}
+
+	// an empty policy removes the current one and keeps the bucket private
+	if err := client.SetBucketPolicy(ctx, bucket.Name, bucket.Policy); err != nil {
+		return fmt.Errorf("set policy of bucket %s: %w", bucket.Name, err)
+	}
+
+	if err := client.SetBucketLifecycle(ctx, bucket.Name, bucketLifecycle(bucket.Lifecycle)); err != nil {
+		return fmt.Errorf("set lifecycle of bucket %s: %w", bucket.Name, err)
+	}
+
+	return nil
+}
+
+func bucketLifecycle(cfg config.BucketLifecycle) *lifecycle.Configuration {
+	configuration := lifecycle.NewConfiguration()
+
+	// temporary uploads that were never attached to a comment
+	if cfg.UploadExpirationDays > 0 {
+		configuration.Rules = append(configuration.Rules, lifecycle.Rule{
+			ID:         expireUploadsRuleID,
+			Status:     lifecycleEnabled,
+			RuleFilter: lifecycle.Filter{Prefix: images.UploadPrefix},
+			Expiration: lifecycle.Expiration{Days: lifecycle.ExpirationDays(cfg.UploadExpirationDays)},
+		})
+	}
+
+	if cfg.TransitionDays > 0 && cfg.TransitionStorageClass != "" {
+		configuration.Rules = append(configuration.Rules, lifecycle.Rule{
+			ID:     transitionImagesRuleID,
+			Status: lifecycleEnabled,
+			Transition: lifecycle.Transition{
+				Days:         lifecycle.ExpirationDays(cfg.TransitionDays),
+				StorageClass: cfg.TransitionStorageClass,
+			},
+		})
+	}
+
+	return configuration
+}
@@ -3,24 +3,33 @@ package minio
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/images"
 	pb "github.com/Verce11o/yata-protos/gen/go/comments"
 	"github.com/minio/minio-go/v7"
 	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 )
 
 const (
-	userCommentsName = "user-comments"
-	imageExpireTime  = time.Hour * 24
+	imageExpireTime = time.Hour * 24
+	uploadPartSize  = 5 << 20
 )
 
 type CommentMinio struct {
-	minio  *minio.Client
-	tracer trace.Tracer
+	minio            *minio.Client
+	bucket           string
+	quarantineBucket string
+	tracer           trace.Tracer
 }
 
-func NewCommentMinio(minio *minio.Client, tracer trace.Tracer) *CommentMinio {
-	return &CommentMinio{minio: minio, tracer: tracer}
+func NewCommentMinio(minio *minio.Client, bucket string, quarantineBucket string, tracer trace.Tracer) *CommentMinio {
+	return &CommentMinio{minio: minio, bucket: bucket, quarantineBucket: quarantineBucket, tracer: tracer}
 }
 
 func (t *CommentMinio) AddCommentImage(ctx context.Context, image *pb.Image, fileName string) error {
@@ -31,30 +40,65 @@ func (t *CommentMinio) AddCommentImage(ctx context.Context, image *pb.Image, fil
 
 	_, err := t.minio.PutObject(
 		ctx,
-		userCommentsName,
+		t.bucket,
 		fileName,
 		reader,
 		reader.Size(),
-		minio.PutObjectOptions{ContentType: image.GetContentType()},
+		minio.PutObjectOptions{
+			ContentType:  image.GetContentType(),
+			UserMetadata: map[string]string{images.OriginalNameMetadata: url.QueryEscape(image.GetName())},
+		},
 	)
 	if err != nil {
-		return err
+		return minioError(err)
+	}
+
+	return nil
+}
+
+func (t *CommentMinio) AddFile(ctx context.Context, fileName string, reader io.Reader) error {
+	ctx, span := t.tracer.Start(ctx, "commentMinio.AddFile")
+	defer span.End()
+
+	// unknown size makes the client stream the reader as a multipart upload
+	_, err := t.minio.PutObject(ctx, t.bucket, fileName, reader, -1, minio.PutObjectOptions{PartSize: uploadPartSize})
+	if err != nil {
+		return minioError(err)
 	}
 
 	return nil
 }
 
+func (t *CommentMinio) GetFile(ctx context.Context, fileName string, limit int64) ([]byte, error) {
+	ctx, span := t.tracer.Start(ctx, "commentMinio.GetFile")
+	defer span.End()
+
+	object, err := t.minio.GetObject(ctx, t.bucket, fileName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, minioError(err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(io.LimitReader(object, limit+1))
+	if err != nil {
+		return nil, minioError(err)
+	}
+
+	return data, nil
+}
+
 func (t *CommentMinio) UpdateCommentImage(ctx context.Context, oldName string, newName string, image *pb.Image) error {
 	ctx, span := t.tracer.Start(ctx, "commentMinio.UpdateCommentImage")
 	defer span.End()
 
-	err := t.DeleteFile(ctx, oldName)
+	// the new image is stored first so a failed upload leaves the old one in place
+	err := t.AddCommentImage(ctx, image, newName)
 
 	if err != nil {
 		return err
 	}
 
-	err = t.AddCommentImage(ctx, image, newName)
+	err = t.DeleteFile(ctx, oldName)
 
 	if err != nil {
 		return err
@@ -63,12 +107,122 @@ func (t *CommentMinio) UpdateCommentImage(ctx context.Context, oldName string, n
 	return nil
 }
 
+func (t *CommentMinio) GetFileURL(ctx context.Context, fileName string) (string, error) {
+	ctx, span := t.tracer.Start(ctx, "commentMinio.GetFileURL")
+	defer span.End()
+
+	fileURL, err := t.minio.PresignedGetObject(ctx, t.bucket, fileName, imageExpireTime, nil)
+	if err != nil {
+		return "", minioError(err)
+	}
+
+	return fileURL.String(), nil
+}
+
+// GetUploadURL presigns a PUT request that only accepts an object of the given type and size.
+func (t *CommentMinio) GetUploadURL(ctx context.Context, fileName string, contentType string, size int64, expires time.Duration) (string, error) {
+	ctx, span := t.tracer.Start(ctx, "commentMinio.GetUploadURL")
+	defer span.End()
+
+	headers := http.Header{}
+	headers.Set("Content-Type", contentType)
+	headers.Set("Content-Length", strconv.FormatInt(size, 10))
+
+	uploadURL, err := t.minio.PresignHeader(ctx, http.MethodPut, t.bucket, fileName, expires, nil, headers)
+	if err != nil {
+		return "", minioError(err)
+	}
+
+	return uploadURL.String(), nil
+}
+
+func (t *CommentMinio) CopyFile(ctx context.Context, srcName string, dstName string) error {
+	ctx, span := t.tracer.Start(ctx, "commentMinio.CopyFile")
+	defer span.End()
+
+	_, err := t.minio.CopyObject(
+		ctx,
+		minio.CopyDestOptions{
+			Bucket:          t.bucket,
+			Object:          dstName,
+			ReplaceMetadata: true,
+			UserMetadata:    map[string]string{images.OriginalNameMetadata: url.QueryEscape(srcName)},
+		},
+		minio.CopySrcOptions{Bucket: t.bucket, Object: srcName},
+	)
+	if err != nil {
+		return minioError(err)
+	}
+
+	return nil
+}
+
+// ListFiles calls fn for every object in the bucket, listing stops at the first error.
+func (t *CommentMinio) ListFiles(ctx context.Context, fn func(file domain.StoredFile) error) error {
+	ctx, span := t.tracer.Start(ctx, "commentMinio.ListFiles")
+	defer span.End()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for object := range t.minio.ListObjects(ctx, t.bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if object.Err != nil {
+			return minioError(object.Err)
+		}
+
+		if err := fn(domain.StoredFile{Name: object.Key, Size: object.Size, LastModified: object.LastModified}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (t *CommentMinio) DeleteFile(ctx context.Context, fileName string) error {
 	ctx, span := t.tracer.Start(ctx, "commentMinio.DeleteFile")
 	defer span.End()
 
-	if err := t.minio.RemoveObject(ctx, userCommentsName, fileName, minio.RemoveObjectOptions{}); err != nil {
-		return err
+	if err := t.minio.RemoveObject(ctx, t.bucket, fileName, minio.RemoveObjectOptions{}); err != nil {
+		return minioError(err)
+	}
+
+	return nil
+}
+
+// QuarantineFile keeps an infected file in a separate bucket that is never served to clients.
+func (t *CommentMinio) QuarantineFile(ctx context.Context, fileName string, data []byte, signature string) error {
+	ctx, span := t.tracer.Start(ctx, "commentMinio.QuarantineFile")
+	defer span.End()
+
+	reader := bytes.NewReader(data)
+
+	_, err := t.minio.PutObject(
+		ctx,
+		t.quarantineBucket,
+		fileName,
+		reader,
+		reader.Size(),
+		minio.PutObjectOptions{UserMetadata: map[string]string{images.SignatureMetadata: signature}},
+	)
+	if err != nil {
+		return minioError(err)
+	}
+
+	return nil
+}
+
+// Ping checks that MinIO answers and the bucket exists.
+func (t *CommentMinio) Ping(ctx context.Context) error {
+	ctx, span := t.tracer.Start(ctx, "commentMinio.Ping")
+	defer span.End()
+
+	exists, err := t.minio.BucketExists(ctx, t.bucket)
+	if err != nil {
+		return minioError(err)
+	}
+
+	if !exists {
+		return domain.UnavailableError(fmt.Sprintf("minio: bucket %s does not exist", t.bucket), nil)
 	}
 
 	return nil
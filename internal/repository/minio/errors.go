@@ -0,0 +1,46 @@
+package minio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"github.com/minio/minio-go/v7"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// minioError classifies err into a domain.Error. Context errors, domain errors and sentinel
+// errors returned by the repository itself are passed through unchanged.
+func minioError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+
+	var response minio.ErrorResponse
+	if errors.As(err, &response) {
+		switch {
+		case response.Code == "NoSuchKey" || response.Code == "NoSuchBucket":
+			return domain.NotFoundError("minio: object not found", fmt.Errorf("%w: %w", grpc_errors.ErrNotFound, err))
+		case response.Code == "SlowDown" || response.StatusCode == http.StatusServiceUnavailable || response.StatusCode >= http.StatusInternalServerError:
+			return domain.UnavailableError("minio", err)
+		}
+
+		return domain.InternalError("minio", err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return domain.UnavailableError("minio", err)
+	}
+
+	return err
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const attachmentColumns = "attachment_id, comment_id, position, image_name, content_type, size, width, height, alt_text, created_at, perceptual_hash, flagged"
+
+func (c *CommentsPostgres) getAttachments(ctx context.Context, commentID string) ([]domain.Attachment, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.getAttachments")
+	defer span.End()
+
+	var attachments []domain.Attachment
+
+	q := "SELECT " + attachmentColumns + " FROM attachments WHERE comment_id = $1 ORDER BY position"
+
+	if err := c.db.SelectContext(ctx, &attachments, q, commentID); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// setAttachments loads the attachments of several comments with a single query.
+func (c *CommentsPostgres) setAttachments(ctx context.Context, comments []*domain.Comment) error {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.setAttachments")
+	defer span.End()
+
+	if len(comments) == 0 {
+		return nil
+	}
+
+	commentIDs := make([]string, 0, len(comments))
+	byID := make(map[uuid.UUID]*domain.Comment, len(comments))
+
+	for _, comment := range comments {
+		commentIDs = append(commentIDs, comment.CommentID.String())
+		byID[comment.CommentID] = comment
+	}
+
+	var attachments []domain.Attachment
+
+	q := "SELECT " + attachmentColumns + " FROM attachments WHERE comment_id = ANY($1) ORDER BY comment_id, position"
+
+	if err := c.db.SelectContext(ctx, &attachments, q, pq.Array(commentIDs)); err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		if comment, ok := byID[attachment.CommentID]; ok {
+			comment.Attachments = append(comment.Attachments, attachment)
+		}
+	}
+
+	return nil
+}
+
+// insertAttachments stores attachments in the given order, their positions are reassigned from it.
+// The stored rows are returned, attachments is left untouched so a rolled back transaction does not
+// make new images look persisted.
+func (c *CommentsPostgres) insertAttachments(ctx context.Context, tx *sqlx.Tx, commentID string, attachments []domain.Attachment) ([]domain.Attachment, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.insertAttachments")
+	defer span.End()
+
+	q := `INSERT INTO attachments (attachment_id, comment_id, position, image_name, content_type, size, width, height, alt_text, created_at, perceptual_hash, flagged)
+		VALUES (COALESCE($1, uuid_generate_v4()), $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10, NOW()), $11, $12) RETURNING ` + attachmentColumns
+
+	stored := make([]domain.Attachment, len(attachments))
+
+	for i, attachment := range attachments {
+		// existing attachments keep their identity when the list is rewritten
+		var attachmentID uuid.NullUUID
+		var createdAt sql.NullTime
+
+		if attachment.AttachmentID != uuid.Nil {
+			attachmentID = uuid.NullUUID{UUID: attachment.AttachmentID, Valid: true}
+			createdAt = sql.NullTime{Time: attachment.CreatedAt, Valid: true}
+		}
+
+		err := tx.QueryRowxContext(ctx, q, attachmentID, commentID, i, attachment.ImageName, attachment.ContentType,
+			attachment.Size, attachment.Width, attachment.Height, attachment.AltText, createdAt, attachment.PerceptualHash,
+			attachment.Flagged).StructScan(&stored[i])
+
+		if err != nil {
+			return nil, err
+		}
+
+		stored[i].URLs = attachment.URLs
+	}
+
+	return stored, nil
+}
+
+func (c *CommentsPostgres) GetAttachment(ctx context.Context, attachmentID string) (*domain.Attachment, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.GetAttachment")
+	defer span.End()
+
+	var attachment domain.Attachment
+
+	q := "SELECT " + attachmentColumns + " FROM attachments WHERE attachment_id = $1"
+
+	if err := c.db.QueryRowxContext(ctx, q, attachmentID).StructScan(&attachment); err != nil {
+		return nil, postgresError(err)
+	}
+
+	return &attachment, nil
+}
+
+// SetAttachmentPerceptualHash stores the hash of an attachment saved before hashing was introduced.
+func (c *CommentsPostgres) SetAttachmentPerceptualHash(ctx context.Context, attachmentID string, perceptualHash int64) error {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.SetAttachmentPerceptualHash")
+	defer span.End()
+
+	_, err := c.db.ExecContext(ctx, "UPDATE attachments SET perceptual_hash = $1 WHERE attachment_id = $2", perceptualHash, attachmentID)
+
+	return postgresError(err)
+}
+
+// GetUnhashedAttachments returns up to limit attachments without a perceptual hash after afterID.
+func (c *CommentsPostgres) GetUnhashedAttachments(ctx context.Context, afterID string, limit int) ([]domain.Attachment, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.GetUnhashedAttachments")
+	defer span.End()
+
+	var attachments []domain.Attachment
+
+	q := "SELECT " + attachmentColumns + " FROM attachments WHERE perceptual_hash IS NULL AND attachment_id > $1 ORDER BY attachment_id LIMIT $2"
+
+	if err := c.db.SelectContext(ctx, &attachments, q, afterID, limit); err != nil {
+		return nil, postgresError(err)
+	}
+
+	return attachments, nil
+}
+
+// GetReferencedImageNames returns which of the given image names are still referenced by a comment.
+func (c *CommentsPostgres) GetReferencedImageNames(ctx context.Context, names []string) (map[string]struct{}, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.GetReferencedImageNames")
+	defer span.End()
+
+	var referenced []string
+
+	q := `SELECT image_name FROM attachments WHERE image_name = ANY($1)
+		UNION SELECT image_name FROM comments WHERE image_name = ANY($1)`
+
+	if err := c.db.SelectContext(ctx, &referenced, q, pq.Array(names)); err != nil {
+		return nil, postgresError(err)
+	}
+
+	result := make(map[string]struct{}, len(referenced))
+
+	for _, name := range referenced {
+		result[name] = struct{}{}
+	}
+
+	return result, nil
+}
+
+// coverImageName is kept in comments.image_name for clients that only know about a single image.
+func coverImageName(attachments []domain.Attachment) string {
+	if len(attachments) == 0 {
+		return ""
+	}
+	return attachments[0].ImageName
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/domain"
+)
+
+const blocklistColumns = "entry_id, perceptual_hash, source_attachment_id, reason, created_by, created_at"
+
+func (c *CommentsPostgres) GetBlocklist(ctx context.Context) ([]domain.BlocklistEntry, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.GetBlocklist")
+	defer span.End()
+
+	var entries []domain.BlocklistEntry
+
+	q := "SELECT " + blocklistColumns + " FROM image_blocklist"
+
+	if err := c.db.SelectContext(ctx, &entries, q); err != nil {
+		return nil, postgresError(err)
+	}
+
+	return entries, nil
+}
+
+// AddBlocklistEntry stores the entry and flags the attachment it was taken from.
+func (c *CommentsPostgres) AddBlocklistEntry(ctx context.Context, entry *domain.BlocklistEntry) (*domain.BlocklistEntry, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.AddBlocklistEntry")
+	defer span.End()
+
+	tx, err := c.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	defer tx.Rollback()
+
+	var created domain.BlocklistEntry
+
+	q := `INSERT INTO image_blocklist (perceptual_hash, source_attachment_id, reason, created_by)
+		VALUES ($1, $2, $3, $4) RETURNING ` + blocklistColumns
+
+	err = tx.QueryRowxContext(ctx, q, entry.PerceptualHash, entry.SourceAttachmentID, entry.Reason, entry.CreatedBy).StructScan(&created)
+
+	if err != nil {
+		return nil, postgresError(err)
+	}
+
+	if entry.SourceAttachmentID.Valid {
+		if _, err := tx.ExecContext(ctx, "UPDATE attachments SET flagged = TRUE WHERE attachment_id = $1", entry.SourceAttachmentID); err != nil {
+			return nil, postgresError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, postgresError(err)
+	}
+
+	return &created, nil
+}
+
+// DeleteBlocklistEntries removes every entry with exactly this hash and returns how many were removed.
+func (c *CommentsPostgres) DeleteBlocklistEntries(ctx context.Context, perceptualHash int64) (int64, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.DeleteBlocklistEntries")
+	defer span.End()
+
+	result, err := c.db.ExecContext(ctx, "DELETE FROM image_blocklist WHERE perceptual_hash = $1", perceptualHash)
+	if err != nil {
+		return 0, postgresError(err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+const changeColumns = "change_id, xact_id, comment_id, tweet_id, user_id, change_type, changed_at"
+
+// insertChange records a change of the comment, for deletions it must run before the row is removed.
+// The row keeps the ID of the writing transaction, which orders the feed without serializing writers.
+func (c *CommentsPostgres) insertChange(ctx context.Context, tx *sqlx.Tx, commentID string, changeType string) error {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.insertChange")
+	defer span.End()
+
+	q := `INSERT INTO comment_changes (comment_id, tweet_id, user_id, change_type)
+		SELECT comment_id, tweet_id, user_id, $2 FROM comments WHERE comment_id = $1`
+
+	_, err := tx.ExecContext(ctx, q, commentID, changeType)
+
+	return err
+}
+
+// GetChanges returns changes after the given position ordered by transaction, then change ID.
+// Only transactions older than every running one are listed, so a change that commits later
+// can never sort before a position already returned to a reader.
+func (c *CommentsPostgres) GetChanges(ctx context.Context, filter domain.ChangeFilter, afterXactID uint64, afterID int64, limit int) ([]domain.CommentChange, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.GetChanges")
+	defer span.End()
+
+	conditions := []string{
+		"(xact_id, change_id) > ($1::text::xid8, $2)",
+		"xact_id < pg_snapshot_xmin(pg_current_snapshot())",
+	}
+	args := []interface{}{afterXactID, afterID}
+
+	if filter.TweetID != "" {
+		args = append(args, filter.TweetID)
+		conditions = append(conditions, fmt.Sprintf("tweet_id = $%d", len(args)))
+	}
+
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	args = append(args, limit)
+
+	q := fmt.Sprintf("SELECT %s FROM comment_changes WHERE %s ORDER BY xact_id, change_id LIMIT $%d",
+		changeColumns, strings.Join(conditions, " AND "), len(args))
+
+	var changes []domain.CommentChange
+
+	if err := c.db.SelectContext(ctx, &changes, q, args...); err != nil {
+		return nil, postgresError(err)
+	}
+
+	return changes, nil
+}
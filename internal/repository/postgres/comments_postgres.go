@@ -3,51 +3,116 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
 	"github.com/Verce11o/yata-comments/internal/lib/pagination"
 	pb "github.com/Verce11o/yata-protos/gen/go/comments"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"go.opentelemetry.io/otel/trace"
-	"google.golang.org/protobuf/types/known/timestamppb"
 	"time"
 )
 
 const (
 	paginationLimit = 10
+	commentColumns  = "comment_id, tweet_id, user_id, text, image_name, created_at, updated_at"
+	uniqueViolation = "23505"
+	// idempotencyKeyIndex rejects a second comment of the user with the same idempotency key
+	idempotencyKeyIndex = "comments_user_id_idempotency_key_idx"
 )
 
 type CommentsPostgres struct {
-	db     *sqlx.DB
-	tracer trace.Tracer
+	db             *sqlx.DB
+	tracer         trace.Tracer
+	searchLanguage string
 }
 
-func NewCommentsPostgres(db *sqlx.DB, tracer trace.Tracer) *CommentsPostgres {
-	return &CommentsPostgres{db: db, tracer: tracer}
+func NewCommentsPostgres(db *sqlx.DB, tracer trace.Tracer, searchLanguage string) *CommentsPostgres {
+	return &CommentsPostgres{db: db, tracer: tracer, searchLanguage: searchLanguage}
 }
 
-func (c *CommentsPostgres) CreateComment(ctx context.Context, input *pb.CreateCommentRequest, imageName string) (string, error) {
+func (c *CommentsPostgres) CreateComment(ctx context.Context, input *pb.CreateCommentRequest, attachments []domain.Attachment, mentions []domain.Mention, hashtags []string, idempotency *domain.IdempotencyRecord) (*domain.Comment, error) {
 	ctx, span := c.tracer.Start(ctx, "commentPostgres.CreateTweet")
 	defer span.End()
 
-	var commentID string
+	var comment domain.Comment
+	var idempotencyKey, requestHash sql.NullString
 
-	q := "INSERT INTO comments (tweet_id, user_id, text, image_name) VALUES ($1, $2, $3, $4) RETURNING comment_id"
+	if idempotency != nil {
+		idempotencyKey = sql.NullString{String: idempotency.Key, Valid: true}
+		requestHash = sql.NullString{String: idempotency.RequestHash, Valid: true}
+	}
 
-	stmt, err := c.db.PreparexContext(ctx, q)
+	tx, err := c.db.BeginTxx(ctx, nil)
 
 	if err != nil {
-		return "", err
+		return nil, postgresError(err)
 	}
+	defer tx.Rollback()
+
+	q := "INSERT INTO comments (tweet_id, user_id, text, image_name, idempotency_key, request_hash) VALUES ($1, $2, $3, $4, $5, $6) RETURNING " + commentColumns
+
+	err = tx.QueryRowxContext(ctx, q, input.GetTweetId(), input.GetUserId(), input.GetText(), coverImageName(attachments), idempotencyKey, requestHash).StructScan(&comment)
 
-	err = stmt.QueryRowxContext(ctx, input.GetTweetId(), input.GetUserId(), input.GetText(), imageName).Scan(&commentID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == idempotencyKeyIndex {
+		return nil, grpc_errors.ErrIdempotencyConflict
+	}
 
 	if err != nil {
-		return "", err
+		return nil, postgresError(err)
+	}
+
+	commentID := comment.CommentID.String()
+
+	stored, err := c.insertAttachments(ctx, tx, commentID, attachments)
+
+	if err != nil {
+		return nil, postgresError(err)
+	}
+
+	if err = c.replaceMentions(ctx, tx, commentID, mentions); err != nil {
+		return nil, postgresError(err)
+	}
+
+	if err = c.replaceHashtags(ctx, tx, commentID, hashtags); err != nil {
+		return nil, postgresError(err)
+	}
+
+	if err = c.updateSearchVector(ctx, tx, commentID); err != nil {
+		return nil, postgresError(err)
+	}
+
+	if err = c.insertChange(ctx, tx, commentID, domain.CommentCreated); err != nil {
+		return nil, postgresError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, postgresError(err)
 	}
 
-	return commentID, nil
+	comment.Attachments = stored
+	comment.Mentions = mentions
+
+	return &comment, nil
+
+}
+
+func (c *CommentsPostgres) GetIdempotencyRecord(ctx context.Context, userID string, key string) (*domain.IdempotencyRecord, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.GetIdempotencyRecord")
+	defer span.End()
+
+	var record domain.IdempotencyRecord
+
+	q := "SELECT idempotency_key, comment_id, request_hash FROM comments WHERE user_id = $1 AND idempotency_key = $2"
+
+	if err := c.db.QueryRowxContext(ctx, q, userID, key).StructScan(&record); err != nil {
+		return nil, postgresError(err)
+	}
 
+	return &record, nil
 }
 
 func (c *CommentsPostgres) GetComment(ctx context.Context, CommentID string) (*domain.Comment, error) {
@@ -56,18 +121,30 @@ func (c *CommentsPostgres) GetComment(ctx context.Context, CommentID string) (*d
 
 	var comment domain.Comment
 
-	q := "SELECT * FROM comments WHERE comment_id = $1"
+	q := "SELECT " + commentColumns + " FROM comments WHERE comment_id = $1"
 
 	err := c.db.QueryRowxContext(ctx, q, CommentID).StructScan(&comment)
 
 	if err != nil {
-		return nil, sql.ErrNoRows
+		return nil, postgresError(err)
+	}
+
+	comment.Attachments, err = c.getAttachments(ctx, CommentID)
+
+	if err != nil {
+		return nil, postgresError(err)
+	}
+
+	comment.Mentions, err = c.getMentions(ctx, CommentID)
+
+	if err != nil {
+		return nil, postgresError(err)
 	}
 
 	return &comment, nil
 }
 
-func (c *CommentsPostgres) GetAllTweetComments(ctx context.Context, cursor string, tweetID string) ([]*pb.Comment, string, error) {
+func (c *CommentsPostgres) GetAllTweetComments(ctx context.Context, cursor string, tweetID string) ([]domain.Comment, string, error) {
 	ctx, span := c.tracer.Start(ctx, "commentPostgres.GetAllComments")
 	defer span.End()
 
@@ -78,57 +155,93 @@ func (c *CommentsPostgres) GetAllTweetComments(ctx context.Context, cursor strin
 	if cursor != "" {
 		createdAt, commentID, err = pagination.DecodeCursor(cursor)
 		if err != nil {
-			return nil, "", err
+			return nil, "", postgresError(err)
 		}
 	}
 
-	q := "SELECT * FROM comments WHERE (created_at, comment_id) > ($1, $2) AND tweet_id = $3 ORDER BY created_at, comment_id LIMIT $4"
+	var comments []domain.Comment
 
-	rows, err := c.db.QueryxContext(ctx, q, createdAt, commentID, tweetID, paginationLimit)
+	q := "SELECT " + commentColumns + " FROM comments WHERE (created_at, comment_id) > ($1, $2) AND tweet_id = $3 ORDER BY created_at, comment_id LIMIT $4"
 
-	if err != nil {
-		return nil, "", err
+	if err := c.db.SelectContext(ctx, &comments, q, createdAt, commentID, tweetID, paginationLimit); err != nil {
+		return nil, "", postgresError(err)
 	}
 
-	var comments []*pb.Comment
-	var latestCreatedAt time.Time
+	refs := make([]*domain.Comment, 0, len(comments))
 
-	for rows.Next() {
-		var item domain.Comment
-		err = rows.StructScan(&item)
-		if err != nil {
-			return nil, "", err
-		}
-		comments = append(comments, &pb.Comment{
-			TweetId:   tweetID,
-			UserId:    item.UserID.String(),
-			CommentId: item.CommentID.String(),
-			Text:      item.Text,
-			CreatedAt: timestamppb.New(item.CreatedAt),
-		})
-		latestCreatedAt = item.CreatedAt
+	for i := range comments {
+		refs = append(refs, &comments[i])
+	}
+
+	if err := c.setMentions(ctx, refs); err != nil {
+		return nil, "", postgresError(err)
+	}
+
+	if err := c.setAttachments(ctx, refs); err != nil {
+		return nil, "", postgresError(err)
 	}
 
 	var nextCursor string
 	if len(comments) > 0 {
-		nextCursor = pagination.EncodeCursor(latestCreatedAt, comments[len(comments)-1].CommentId)
+		last := comments[len(comments)-1]
+		nextCursor = pagination.EncodeCursor(last.CreatedAt, last.CommentID.String())
 	}
 
 	return comments, nextCursor, nil
 }
 
-func (c *CommentsPostgres) UpdateComment(ctx context.Context, input *pb.UpdateCommentRequest, imageName string) (*domain.Comment, error) {
+func (c *CommentsPostgres) UpdateComment(ctx context.Context, input *pb.UpdateCommentRequest, attachments []domain.Attachment, mentions []domain.Mention, hashtags []string) (*domain.Comment, error) {
 	ctx, span := c.tracer.Start(ctx, "commentPostgres.Updatecomment")
 	defer span.End()
 
 	var comment domain.Comment
 
-	q := "UPDATE comments SET text = $1, image_name = $2, updated_at = CURRENT_TIMESTAMP WHERE comment_id = $3 RETURNING *"
+	tx, err := c.db.BeginTxx(ctx, nil)
+
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	defer tx.Rollback()
+
+	q := "UPDATE comments SET text = $1, image_name = $2, updated_at = CURRENT_TIMESTAMP WHERE comment_id = $3 RETURNING " + commentColumns
+
+	if err := tx.QueryRowxContext(ctx, q, input.GetText(), coverImageName(attachments), input.GetCommentId()).StructScan(&comment); err != nil {
+		return nil, postgresError(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM attachments WHERE comment_id = $1", input.GetCommentId()); err != nil {
+		return nil, postgresError(err)
+	}
+
+	stored, err := c.insertAttachments(ctx, tx, input.GetCommentId(), attachments)
+
+	if err != nil {
+		return nil, postgresError(err)
+	}
+
+	if err := c.replaceMentions(ctx, tx, input.GetCommentId(), mentions); err != nil {
+		return nil, postgresError(err)
+	}
+
+	if err := c.replaceHashtags(ctx, tx, input.GetCommentId(), hashtags); err != nil {
+		return nil, postgresError(err)
+	}
+
+	if err := c.updateSearchVector(ctx, tx, input.GetCommentId()); err != nil {
+		return nil, postgresError(err)
+	}
+
+	if err := c.insertChange(ctx, tx, input.GetCommentId(), domain.CommentUpdated); err != nil {
+		return nil, postgresError(err)
+	}
 
-	if err := c.db.QueryRowxContext(ctx, q, input.GetText(), imageName, input.GetCommentId()).StructScan(&comment); err != nil {
-		return nil, err
+	if err := tx.Commit(); err != nil {
+		return nil, postgresError(err)
 	}
 
+	comment.Attachments = stored
+	comment.Mentions = mentions
+
 	return &comment, nil
 }
 
@@ -136,23 +249,107 @@ func (c *CommentsPostgres) DeleteComment(ctx context.Context, commentID string)
 	ctx, span := c.tracer.Start(ctx, "commentPostgres.DeleteComment")
 	defer span.End()
 
+	tx, err := c.db.BeginTxx(ctx, nil)
+
+	if err != nil {
+		return postgresError(err)
+	}
+	defer tx.Rollback()
+
+	if err := c.insertChange(ctx, tx, commentID, domain.CommentDeleted); err != nil {
+		return postgresError(err)
+	}
+
 	q := "DELETE FROM comments WHERE comment_id = $1"
 
-	res, err := c.db.ExecContext(ctx, q, commentID)
+	res, err := tx.ExecContext(ctx, q, commentID)
 
 	if err != nil {
-		return err
+		return postgresError(err)
 	}
 
 	rowsAffected, err := res.RowsAffected()
 
 	if err != nil {
-		return err
+		return postgresError(err)
 	}
 
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return domain.NotFoundError("postgres: comment not found", sql.ErrNoRows)
+	}
+
+	return postgresError(tx.Commit())
+}
+
+// GetLegacyImageComments returns comments with client-named images after cursor, oldest first.
+// The returned cursor is empty once the last page has been read.
+func (c *CommentsPostgres) GetLegacyImageComments(ctx context.Context, cursor string, limit int) ([]domain.Comment, string, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.GetLegacyImageComments")
+	defer span.End()
+
+	var createdAt time.Time
+	var commentID uuid.UUID
+	var err error
+
+	if cursor != "" {
+		createdAt, commentID, err = pagination.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", postgresError(err)
+		}
+	}
+
+	var comments []domain.Comment
+
+	q := `SELECT ` + commentColumns + ` FROM comments WHERE image_name <> '' AND image_name NOT LIKE '%/%'
+		AND (created_at, comment_id) > ($1, $2) ORDER BY created_at, comment_id LIMIT $3`
+
+	if err := c.db.SelectContext(ctx, &comments, q, createdAt, commentID, limit); err != nil {
+		return nil, "", postgresError(err)
+	}
+
+	var nextCursor string
+	if len(comments) == limit {
+		last := comments[len(comments)-1]
+		nextCursor = pagination.EncodeCursor(last.CreatedAt, last.CommentID.String())
+	}
+
+	return comments, nextCursor, nil
+}
+
+func (c *CommentsPostgres) UpdateCommentImageName(ctx context.Context, commentID string, oldName string, newName string) error {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.UpdateCommentImageName")
+	defer span.End()
+
+	tx, err := c.db.BeginTxx(ctx, nil)
+
+	if err != nil {
+		return postgresError(err)
+	}
+	defer tx.Rollback()
+
+	q := "UPDATE comments SET image_name = $1 WHERE comment_id = $2 AND image_name = $3"
+
+	res, err := tx.ExecContext(ctx, q, newName, commentID, oldName)
+
+	if err != nil {
+		return postgresError(err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+
+	if err != nil {
+		return postgresError(err)
+	}
+
+	if rowsAffected == 0 {
+		return domain.NotFoundError("postgres: comment not found", sql.ErrNoRows)
+	}
+
+	q = "UPDATE attachments SET image_name = $1 WHERE comment_id = $2 AND image_name = $3"
+
+	if _, err := tx.ExecContext(ctx, q, newName, commentID, oldName); err != nil {
+		return postgresError(err)
 	}
 
-	return nil
+	return postgresError(tx.Commit())
 }
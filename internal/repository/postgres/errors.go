@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/lib/pq"
+	"io"
+	"net"
+	"syscall"
+)
+
+// postgresError classifies err into a domain.Error. Context errors, domain errors and sentinel
+// errors returned by the repository itself are passed through unchanged.
+func postgresError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NotFoundError("postgres: record not found", err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return domain.NewError(pqErrorKind(pqErr), "postgres", err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return domain.UnavailableError("postgres", err)
+	}
+
+	return err
+}
+
+// pqErrorKind maps SQLSTATE classes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+func pqErrorKind(err *pq.Error) domain.ErrorKind {
+	switch err.Code.Class() {
+	case "08", "53", "57": // connection exception, insufficient resources, operator intervention
+		return domain.KindUnavailable
+	case "40": // serialization failure and deadlock
+		return domain.KindAborted
+	case "22": // data exception, e.g. malformed uuid input
+		return domain.KindInvalid
+	case "23":
+		if err.Code == uniqueViolation {
+			return domain.KindConflict
+		}
+		return domain.KindInvalid
+	}
+
+	return domain.KindInternal
+}
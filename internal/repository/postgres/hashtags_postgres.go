@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/pagination"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// replaceHashtags rewrites the hashtags of a comment, tags are expected to be distinct and normalized.
+func (c *CommentsPostgres) replaceHashtags(ctx context.Context, tx *sqlx.Tx, commentID string, hashtags []string) error {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.replaceHashtags")
+	defer span.End()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM hashtags WHERE comment_id = $1", commentID); err != nil {
+		return err
+	}
+
+	for _, tag := range hashtags {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO hashtags (comment_id, tag) VALUES ($1, $2)", commentID, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReindexHashtags rewrites the hashtags of up to limit comments after afterID with the tags extracted by
+// hashtags and returns the last reindexed comment, it backfills comments written before tags were stored.
+func (c *CommentsPostgres) ReindexHashtags(ctx context.Context, afterID string, limit int, hashtags func(text string) []string) (string, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.ReindexHashtags")
+	defer span.End()
+
+	var comments []domain.Comment
+
+	q := "SELECT " + commentColumns + " FROM comments WHERE comment_id > $1 ORDER BY comment_id LIMIT $2"
+
+	if err := c.db.SelectContext(ctx, &comments, q, afterID, limit); err != nil {
+		return "", postgresError(err)
+	}
+
+	tx, err := c.db.BeginTxx(ctx, nil)
+
+	if err != nil {
+		return "", postgresError(err)
+	}
+	defer tx.Rollback()
+
+	for _, comment := range comments {
+		if err := c.replaceHashtags(ctx, tx, comment.CommentID.String(), hashtags(comment.Text)); err != nil {
+			return "", postgresError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", postgresError(err)
+	}
+
+	if len(comments) < limit {
+		return "", nil
+	}
+
+	return comments[len(comments)-1].CommentID.String(), nil
+}
+
+func (c *CommentsPostgres) GetHashtagComments(ctx context.Context, tag string, cursor string) ([]domain.Comment, string, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.GetHashtagComments")
+	defer span.End()
+
+	var createdAt time.Time
+	var commentID uuid.UUID
+	var err error
+
+	if cursor != "" {
+		createdAt, commentID, err = pagination.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", postgresError(err)
+		}
+	}
+
+	var comments []domain.Comment
+
+	q := `SELECT ` + commentColumns + ` FROM comments WHERE comment_id IN (SELECT comment_id FROM hashtags WHERE tag = $1)
+		AND (created_at, comment_id) > ($2, $3) ORDER BY created_at, comment_id LIMIT $4`
+
+	if err := c.db.SelectContext(ctx, &comments, q, tag, createdAt, commentID, paginationLimit); err != nil {
+		return nil, "", postgresError(err)
+	}
+
+	refs := make([]*domain.Comment, 0, len(comments))
+
+	for i := range comments {
+		refs = append(refs, &comments[i])
+	}
+
+	if err := c.setMentions(ctx, refs); err != nil {
+		return nil, "", postgresError(err)
+	}
+
+	if err := c.setAttachments(ctx, refs); err != nil {
+		return nil, "", postgresError(err)
+	}
+
+	var nextCursor string
+	if len(comments) > 0 {
+		last := comments[len(comments)-1]
+		nextCursor = pagination.EncodeCursor(last.CreatedAt, last.CommentID.String())
+	}
+
+	return comments, nextCursor, nil
+}
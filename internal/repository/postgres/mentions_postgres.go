@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const mentionColumns = "comment_id, user_id, username, start_offset, end_offset"
+
+func (c *CommentsPostgres) getMentions(ctx context.Context, commentID string) ([]domain.Mention, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.getMentions")
+	defer span.End()
+
+	var mentions []domain.Mention
+
+	q := "SELECT " + mentionColumns + " FROM mentions WHERE comment_id = $1 ORDER BY start_offset"
+
+	if err := c.db.SelectContext(ctx, &mentions, q, commentID); err != nil {
+		return nil, err
+	}
+
+	return mentions, nil
+}
+
+// setMentions loads the mentions of several comments with a single query.
+func (c *CommentsPostgres) setMentions(ctx context.Context, comments []*domain.Comment) error {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.setMentions")
+	defer span.End()
+
+	if len(comments) == 0 {
+		return nil
+	}
+
+	commentIDs := make([]string, 0, len(comments))
+	byID := make(map[uuid.UUID]*domain.Comment, len(comments))
+
+	for _, comment := range comments {
+		commentIDs = append(commentIDs, comment.CommentID.String())
+		byID[comment.CommentID] = comment
+	}
+
+	var mentions []domain.Mention
+
+	q := "SELECT " + mentionColumns + " FROM mentions WHERE comment_id = ANY($1) ORDER BY comment_id, start_offset"
+
+	if err := c.db.SelectContext(ctx, &mentions, q, pq.Array(commentIDs)); err != nil {
+		return err
+	}
+
+	for _, mention := range mentions {
+		if comment, ok := byID[mention.CommentID]; ok {
+			comment.Mentions = append(comment.Mentions, mention)
+		}
+	}
+
+	return nil
+}
+
+// replaceMentions rewrites the mentions of a comment, the stored list always matches its current text.
+func (c *CommentsPostgres) replaceMentions(ctx context.Context, tx *sqlx.Tx, commentID string, mentions []domain.Mention) error {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.replaceMentions")
+	defer span.End()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mentions WHERE comment_id = $1", commentID); err != nil {
+		return err
+	}
+
+	q := "INSERT INTO mentions (" + mentionColumns + ") VALUES ($1, $2, $3, $4, $5)"
+
+	for i := range mentions {
+		mention := &mentions[i]
+
+		if _, err := tx.ExecContext(ctx, q, commentID, mention.UserID, mention.Username, mention.Start, mention.End); err != nil {
+			return err
+		}
+
+		mention.CommentID = uuid.MustParse(commentID)
+	}
+
+	return nil
+}
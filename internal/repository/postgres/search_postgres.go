@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/pagination"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+// searchDocument is the text indexed for a comment: its own text followed by the alt texts of its images.
+const searchDocument = `coalesce(text, '') || ' ' || coalesce((SELECT string_agg(alt_text, ' ') FROM attachments
+	WHERE attachments.comment_id = comments.comment_id), '')`
+
+// updateSearchVector reindexes the comment, it must run after its attachments are written.
+func (c *CommentsPostgres) updateSearchVector(ctx context.Context, tx *sqlx.Tx, commentID string) error {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.updateSearchVector")
+	defer span.End()
+
+	q := "UPDATE comments SET search_vector = to_tsvector($1::regconfig, " + searchDocument + ") WHERE comment_id = $2"
+
+	_, err := tx.ExecContext(ctx, q, c.searchLanguage, commentID)
+
+	return err
+}
+
+// ReindexSearch rebuilds up to limit search vectors after afterID and returns the last reindexed comment,
+// it is needed after the search language changes.
+func (c *CommentsPostgres) ReindexSearch(ctx context.Context, afterID string, limit int) (string, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.ReindexSearch")
+	defer span.End()
+
+	var commentIDs []string
+
+	q := `UPDATE comments SET search_vector = to_tsvector($1::regconfig, ` + searchDocument + `)
+		WHERE comment_id IN (SELECT comment_id FROM comments WHERE comment_id > $2 ORDER BY comment_id LIMIT $3)
+		RETURNING comment_id`
+
+	if err := c.db.SelectContext(ctx, &commentIDs, q, c.searchLanguage, afterID, limit); err != nil {
+		return "", postgresError(err)
+	}
+
+	if len(commentIDs) < limit {
+		return "", nil
+	}
+
+	last := commentIDs[0]
+
+	for _, commentID := range commentIDs {
+		if commentID > last {
+			last = commentID
+		}
+	}
+
+	return last, nil
+}
+
+// SearchComments returns comments matching the web-search style query, best matches first.
+func (c *CommentsPostgres) SearchComments(ctx context.Context, query string, filter domain.SearchFilter, cursor string, limit int) ([]domain.SearchResult, string, error) {
+	ctx, span := c.tracer.Start(ctx, "commentPostgres.SearchComments")
+	defer span.End()
+
+	args := []interface{}{c.searchLanguage, query}
+	conditions := []string{"search_vector @@ query"}
+
+	if cursor != "" {
+		rank, commentID, err := pagination.DecodeRankCursor(cursor)
+		if err != nil {
+			return nil, "", postgresError(err)
+		}
+
+		args = append(args, rank, commentID)
+		conditions = append(conditions, fmt.Sprintf("(ts_rank(search_vector, query), comment_id) < ($%d::real, $%d)", len(args)-1, len(args)))
+	}
+
+	if filter.TweetID != "" {
+		args = append(args, filter.TweetID)
+		conditions = append(conditions, fmt.Sprintf("tweet_id = $%d", len(args)))
+	}
+
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	args = append(args, limit)
+
+	q := fmt.Sprintf(`SELECT %s, ts_rank(search_vector, query) AS rank, ts_headline($1::regconfig, text, query, '%s') AS snippet
+		FROM comments, websearch_to_tsquery($1::regconfig, $2) AS query
+		WHERE %s
+		ORDER BY rank DESC, comment_id DESC
+		LIMIT $%d`, commentColumns, headlineOptions, strings.Join(conditions, " AND "), len(args))
+
+	rows, err := c.db.QueryxContext(ctx, q, args...)
+
+	if err != nil {
+		return nil, "", postgresError(err)
+	}
+	defer rows.Close()
+
+	var results []domain.SearchResult
+
+	for rows.Next() {
+		var item struct {
+			domain.Comment
+			Rank    float32 `db:"rank"`
+			Snippet string  `db:"snippet"`
+		}
+
+		if err := rows.StructScan(&item); err != nil {
+			return nil, "", postgresError(err)
+		}
+
+		results = append(results, domain.SearchResult{Comment: item.Comment, Rank: item.Rank, Snippet: item.Snippet})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", postgresError(err)
+	}
+
+	refs := make([]*domain.Comment, 0, len(results))
+
+	for i := range results {
+		refs = append(refs, &results[i].Comment)
+	}
+
+	if err := c.setMentions(ctx, refs); err != nil {
+		return nil, "", postgresError(err)
+	}
+
+	if err := c.setAttachments(ctx, refs); err != nil {
+		return nil, "", postgresError(err)
+	}
+
+	var nextCursor string
+	if len(results) == limit {
+		last := results[len(results)-1]
+		nextCursor = pagination.EncodeRankCursor(last.Rank, last.Comment.CommentID.String())
+	}
+
+	return results, nextCursor, nil
+}
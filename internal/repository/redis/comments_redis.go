@@ -3,7 +3,6 @@ package redis
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/Verce11o/yata-comments/internal/domain"
 	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
@@ -13,9 +12,32 @@ import (
 )
 
 const (
-	commentTTL = 3600
+	commentTTL        = 3600
+	idempotencyKeyTTL = 86400
+	uploadTTL         = 3600
+
+	commentEventsTTL    = 86400
+	commentEventsMaxLen = 1000
+	commentEventField   = "event"
+	firstEventCursor    = "0-0"
+
+	mentionEventsKey    = "mention-events"
+	mentionEventsMaxLen = 100000
 )
 
+// publishCommentEvent appends an event to the stream once per event ID. KEYS[1] is the stream and KEYS[2]
+// remembers the entry ID of the event, so a retry after a lost reply returns the entry already added.
+var publishCommentEvent = redis.NewScript(`
+local id = redis.call("GET", KEYS[2])
+if id then
+	return id
+end
+id = redis.call("XADD", KEYS[1], "MAXLEN", "~", ARGV[1], "*", ARGV[2], ARGV[3])
+redis.call("EXPIRE", KEYS[1], ARGV[4])
+redis.call("SET", KEYS[2], id, "EX", ARGV[4])
+return id
+`)
+
 type CommentsRedis struct {
 	client *redis.Client
 	tracer trace.Tracer
@@ -32,10 +54,7 @@ func (r *CommentsRedis) GetCommentByIDCtx(ctx context.Context, commentID string)
 	commentBytes, err := r.client.Get(ctx, r.createKey(commentID)).Bytes()
 
 	if err != nil {
-		if !errors.Is(err, redis.Nil) {
-			return nil, grpc_errors.ErrNotFound
-		}
-		return nil, err
+		return nil, redisError(err)
 	}
 
 	var comment domain.Comment
@@ -57,14 +76,288 @@ func (r *CommentsRedis) SetByIDCtx(ctx context.Context, commentID string, commen
 		return err
 	}
 
-	return r.client.Set(ctx, r.createKey(commentID), commentBytes, time.Second*time.Duration(commentTTL)).Err()
+	return redisError(r.client.Set(ctx, r.createKey(commentID), commentBytes, time.Second*time.Duration(commentTTL)).Err())
 }
 
 func (r *CommentsRedis) DeleteCommentByIDCtx(ctx context.Context, commentID string) error {
 	ctx, span := r.tracer.Start(ctx, "commentRedis.DeleteCommentByIDCtx")
 	defer span.End()
 
-	return r.client.Del(ctx, r.createKey(commentID)).Err()
+	return redisError(r.client.Del(ctx, r.createKey(commentID)).Err())
+}
+
+func (r *CommentsRedis) GetIdempotencyKeyCtx(ctx context.Context, userID string, key string) (*domain.IdempotencyRecord, error) {
+	ctx, span := r.tracer.Start(ctx, "commentRedis.GetIdempotencyKeyCtx")
+	defer span.End()
+
+	recordBytes, err := r.client.Get(ctx, r.createIdempotencyKey(userID, key)).Bytes()
+
+	if err != nil {
+		return nil, redisError(err)
+	}
+
+	var record domain.IdempotencyRecord
+
+	if err = json.Unmarshal(recordBytes, &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (r *CommentsRedis) SetIdempotencyKeyCtx(ctx context.Context, userID string, key string, record *domain.IdempotencyRecord) error {
+	ctx, span := r.tracer.Start(ctx, "commentRedis.SetIdempotencyKeyCtx")
+	defer span.End()
+
+	recordBytes, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	return redisError(r.client.Set(ctx, r.createIdempotencyKey(userID, key), recordBytes, time.Second*time.Duration(idempotencyKeyTTL)).Err())
+}
+
+func (r *CommentsRedis) GetUploadCtx(ctx context.Context, token string) (*domain.Upload, error) {
+	ctx, span := r.tracer.Start(ctx, "commentRedis.GetUploadCtx")
+	defer span.End()
+
+	uploadBytes, err := r.client.Get(ctx, r.createUploadKey(token)).Bytes()
+
+	if err != nil {
+		return nil, redisError(err)
+	}
+
+	var upload domain.Upload
+
+	if err = json.Unmarshal(uploadBytes, &upload); err != nil {
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+func (r *CommentsRedis) SetUploadCtx(ctx context.Context, upload *domain.Upload) error {
+	ctx, span := r.tracer.Start(ctx, "commentRedis.SetUploadCtx")
+	defer span.End()
+
+	uploadBytes, err := json.Marshal(upload)
+
+	if err != nil {
+		return err
+	}
+
+	return redisError(r.client.Set(ctx, r.createUploadKey(upload.Token), uploadBytes, time.Second*time.Duration(uploadTTL)).Err())
+}
+
+func (r *CommentsRedis) DeleteUploadCtx(ctx context.Context, token string) error {
+	ctx, span := r.tracer.Start(ctx, "commentRedis.DeleteUploadCtx")
+	defer span.End()
+
+	return redisError(r.client.Del(ctx, r.createUploadKey(token)).Err())
+}
+
+// PublishCommentEventCtx appends the event to the tweet's event stream and notifies subscribers on every replica.
+// Only the stream is authoritative, the notification just wakes subscribers up to read it. The event is
+// stored once per EventID, so a call that failed, even after Redis applied it, can be retried with the same event.
+func (r *CommentsRedis) PublishCommentEventCtx(ctx context.Context, event *domain.CommentEvent) error {
+	ctx, span := r.tracer.Start(ctx, "commentRedis.PublishCommentEventCtx")
+	defer span.End()
+
+	eventBytes, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	key := r.createEventsKey(event.TweetID.String())
+	keys := []string{key, r.createPublishedEventKey(event.EventID.String())}
+
+	cursor, err := publishCommentEvent.Run(ctx, r.client, keys, commentEventsMaxLen, commentEventField, eventBytes, commentEventsTTL).Text()
+
+	if err != nil {
+		return redisError(err)
+	}
+
+	event.Cursor = cursor
+
+	// subscribers also poll the stream, a lost notification only delays delivery
+	_ = r.client.Publish(ctx, key, event.Cursor).Err()
+
+	return nil
+}
+
+// GetLastCommentEventCursorCtx returns the cursor of the latest event, subscribing from it skips the history.
+func (r *CommentsRedis) GetLastCommentEventCursorCtx(ctx context.Context, tweetID string) (string, error) {
+	ctx, span := r.tracer.Start(ctx, "commentRedis.GetLastCommentEventCursorCtx")
+	defer span.End()
+
+	messages, err := r.client.XRevRangeN(ctx, r.createEventsKey(tweetID), "+", "-", 1).Result()
+
+	if err != nil {
+		return "", redisError(err)
+	}
+
+	if len(messages) == 0 {
+		return firstEventCursor, nil
+	}
+
+	return messages[0].ID, nil
+}
+
+// GetCommentEventsCtx returns up to limit events following cursor.
+// A cursor older than the retained history yields ErrCursorExpired, events may have been trimmed since.
+func (r *CommentsRedis) GetCommentEventsCtx(ctx context.Context, tweetID string, cursor string, limit int64) ([]domain.CommentEvent, error) {
+	ctx, span := r.tracer.Start(ctx, "commentRedis.GetCommentEventsCtx")
+	defer span.End()
+
+	after, err := parseStreamID(cursor)
+
+	if err != nil {
+		return nil, redisError(err)
+	}
+
+	key := r.createEventsKey(tweetID)
+
+	if err := r.checkCursorRetained(ctx, key, after); err != nil {
+		return nil, redisError(err)
+	}
+
+	messages, err := r.client.XRangeN(ctx, key, "("+cursor, "+", limit).Result()
+
+	if err != nil {
+		return nil, redisError(err)
+	}
+
+	events := make([]domain.CommentEvent, 0, len(messages))
+
+	for _, message := range messages {
+		eventString, ok := message.Values[commentEventField].(string)
+
+		if !ok {
+			return nil, fmt.Errorf("comment event %s has no payload", message.ID)
+		}
+
+		var event domain.CommentEvent
+
+		if err := json.Unmarshal([]byte(eventString), &event); err != nil {
+			return nil, err
+		}
+
+		event.Cursor = message.ID
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// checkCursorRetained fails with ErrCursorExpired when events following after may have been dropped,
+// either trimmed from the head of the stream or lost with the whole stream when its TTL ran out.
+func (r *CommentsRedis) checkCursorRetained(ctx context.Context, key string, after streamID) error {
+	if after == (streamID{}) {
+		return nil
+	}
+
+	messages, err := r.client.XRangeN(ctx, key, "-", "+", 1).Result()
+
+	if err != nil {
+		return err
+	}
+
+	// the cursor came from an entry of this stream, so a missing stream has expired since
+	if len(messages) == 0 {
+		return grpc_errors.ErrCursorExpired
+	}
+
+	first, err := parseStreamID(messages[0].ID)
+
+	if err != nil {
+		return err
+	}
+
+	if after.less(first) {
+		return grpc_errors.ErrCursorExpired
+	}
+
+	return nil
+}
+
+// SubscribeCommentEventsCtx notifies about new events of the tweet until ctx is done.
+// The subscription is active when the method returns, so events published afterwards are never missed.
+func (r *CommentsRedis) SubscribeCommentEventsCtx(ctx context.Context, tweetID string) (<-chan struct{}, error) {
+	ctx, span := r.tracer.Start(ctx, "commentRedis.SubscribeCommentEventsCtx")
+	defer span.End()
+
+	pubsub := r.client.Subscribe(ctx, r.createEventsKey(tweetID))
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, redisError(err)
+	}
+
+	notifications := make(chan struct{}, 1)
+	messages := pubsub.Channel()
+
+	go func() {
+		defer close(notifications)
+		defer pubsub.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				// pending notifications are merged, the subscriber reads every new event anyway
+				select {
+				case notifications <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return notifications, nil
+}
+
+// PublishMentionEventCtx appends the event to the stream read by the notification workers through a consumer group.
+func (r *CommentsRedis) PublishMentionEventCtx(ctx context.Context, event *domain.MentionEvent) error {
+	ctx, span := r.tracer.Start(ctx, "commentRedis.PublishMentionEventCtx")
+	defer span.End()
+
+	eventBytes, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	err = r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: mentionEventsKey,
+		MaxLen: mentionEventsMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{commentEventField: eventBytes},
+	}).Err()
+
+	return redisError(err)
+}
+
+func (r *CommentsRedis) createEventsKey(tweetID string) string {
+	return fmt.Sprintf("comment-events:%s", tweetID)
+}
+
+func (r *CommentsRedis) createPublishedEventKey(eventID string) string {
+	return fmt.Sprintf("comment-event-published:%s", eventID)
+}
+
+func (r *CommentsRedis) createUploadKey(token string) string {
+	return fmt.Sprintf("upload:%s", token)
+}
+
+func (r *CommentsRedis) createIdempotencyKey(userID string, key string) string {
+	return fmt.Sprintf("idempotency:%s:%s", userID, key)
 }
 
 func (r *CommentsRedis) createKey(key string) string {
@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// unavailablePrefixes are server replies for a node that cannot serve the command right now.
+var unavailablePrefixes = []string{"LOADING", "BUSY", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN", "READONLY"}
+
+// redisError classifies err into a domain.Error. Context errors, domain errors and sentinel
+// errors returned by the repository itself are passed through unchanged.
+func redisError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+
+	if errors.Is(err, redis.Nil) {
+		return domain.NotFoundError("redis: key not found", err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, redis.ErrClosed) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return domain.UnavailableError("redis", err)
+	}
+
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		for _, prefix := range unavailablePrefixes {
+			if strings.HasPrefix(redisErr.Error(), prefix) {
+				return domain.UnavailableError("redis", err)
+			}
+		}
+
+		return domain.InternalError("redis", err)
+	}
+
+	return err
+}
@@ -0,0 +1,26 @@
+package redis
+
+import "github.com/Verce11o/yata-comments/internal/lib/pagination"
+
+// streamID is a parsed Redis stream entry ID, they order by time first and sequence second.
+type streamID struct {
+	ms  uint64
+	seq uint64
+}
+
+func parseStreamID(id string) (streamID, error) {
+	ms, seq, err := pagination.DecodeEventCursor(id)
+
+	if err != nil {
+		return streamID{}, err
+	}
+
+	return streamID{ms: ms, seq: seq}, nil
+}
+
+func (id streamID) less(other streamID) bool {
+	if id.ms != other.ms {
+		return id.ms < other.ms
+	}
+	return id.seq < other.seq
+}
@@ -4,24 +4,62 @@ import (
 	"context"
 	"github.com/Verce11o/yata-comments/internal/domain"
 	pb "github.com/Verce11o/yata-protos/gen/go/comments"
+	"github.com/google/uuid"
+	"io"
+	"time"
 )
 
 type RedisRepository interface { // maybe refactor ?
 	GetCommentByIDCtx(ctx context.Context, key string) (*domain.Comment, error)
 	SetByIDCtx(ctx context.Context, commentID string, comment *domain.Comment) error
 	DeleteCommentByIDCtx(ctx context.Context, commentID string) error
+	GetIdempotencyKeyCtx(ctx context.Context, userID string, key string) (*domain.IdempotencyRecord, error)
+	SetIdempotencyKeyCtx(ctx context.Context, userID string, key string, record *domain.IdempotencyRecord) error
+	GetUploadCtx(ctx context.Context, token string) (*domain.Upload, error)
+	SetUploadCtx(ctx context.Context, upload *domain.Upload) error
+	DeleteUploadCtx(ctx context.Context, token string) error
+	PublishCommentEventCtx(ctx context.Context, event *domain.CommentEvent) error
+	GetLastCommentEventCursorCtx(ctx context.Context, tweetID string) (string, error)
+	GetCommentEventsCtx(ctx context.Context, tweetID string, cursor string, limit int64) ([]domain.CommentEvent, error)
+	SubscribeCommentEventsCtx(ctx context.Context, tweetID string) (<-chan struct{}, error)
+	PublishMentionEventCtx(ctx context.Context, event *domain.MentionEvent) error
 }
 
 type PostgresRepository interface {
-	CreateComment(ctx context.Context, input *pb.CreateCommentRequest, imageName string) (string, error)
+	CreateComment(ctx context.Context, input *pb.CreateCommentRequest, attachments []domain.Attachment, mentions []domain.Mention, hashtags []string, idempotency *domain.IdempotencyRecord) (*domain.Comment, error)
+	GetIdempotencyRecord(ctx context.Context, userID string, key string) (*domain.IdempotencyRecord, error)
 	GetComment(ctx context.Context, CommentID string) (*domain.Comment, error)
-	GetAllTweetComments(ctx context.Context, cursor string, tweetID string) ([]*pb.Comment, string, error)
-	UpdateComment(ctx context.Context, input *pb.UpdateCommentRequest, imageName string) (*domain.Comment, error)
+	GetAllTweetComments(ctx context.Context, cursor string, tweetID string) ([]domain.Comment, string, error)
+	UpdateComment(ctx context.Context, input *pb.UpdateCommentRequest, attachments []domain.Attachment, mentions []domain.Mention, hashtags []string) (*domain.Comment, error)
 	DeleteComment(ctx context.Context, CommentID string) error
+	GetLegacyImageComments(ctx context.Context, cursor string, limit int) ([]domain.Comment, string, error)
+	UpdateCommentImageName(ctx context.Context, commentID string, oldName string, newName string) error
+	GetReferencedImageNames(ctx context.Context, names []string) (map[string]struct{}, error)
+	GetAttachment(ctx context.Context, attachmentID string) (*domain.Attachment, error)
+	SetAttachmentPerceptualHash(ctx context.Context, attachmentID string, perceptualHash int64) error
+	GetBlocklist(ctx context.Context) ([]domain.BlocklistEntry, error)
+	AddBlocklistEntry(ctx context.Context, entry *domain.BlocklistEntry) (*domain.BlocklistEntry, error)
+	DeleteBlocklistEntries(ctx context.Context, perceptualHash int64) (int64, error)
+	GetChanges(ctx context.Context, filter domain.ChangeFilter, afterXactID uint64, afterID int64, limit int) ([]domain.CommentChange, error)
+	SearchComments(ctx context.Context, query string, filter domain.SearchFilter, cursor string, limit int) ([]domain.SearchResult, string, error)
+	GetHashtagComments(ctx context.Context, tag string, cursor string) ([]domain.Comment, string, error)
 }
 
-type MinioRepository interface {
+// UserDirectory resolves usernames to user IDs, unknown usernames are left out of the result.
+type UserDirectory interface {
+	LookupUsernames(ctx context.Context, usernames []string) (map[string]uuid.UUID, error)
+}
+
+type StorageRepository interface {
 	AddCommentImage(ctx context.Context, image *pb.Image, fileName string) error
+	AddFile(ctx context.Context, fileName string, reader io.Reader) error
+	GetFile(ctx context.Context, fileName string, limit int64) ([]byte, error)
 	UpdateCommentImage(ctx context.Context, oldName string, newName string, image *pb.Image) error
+	CopyFile(ctx context.Context, srcName string, dstName string) error
+	GetFileURL(ctx context.Context, fileName string) (string, error)
+	GetUploadURL(ctx context.Context, fileName string, contentType string, size int64, expires time.Duration) (string, error)
 	DeleteFile(ctx context.Context, fileName string) error
+	QuarantineFile(ctx context.Context, fileName string, data []byte, signature string) error
+	ListFiles(ctx context.Context, fn func(file domain.StoredFile) error) error
+	Ping(ctx context.Context) error
 }
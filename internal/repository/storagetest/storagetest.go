@@ -0,0 +1,370 @@
+// Package storagetest checks that a storage backend behaves the way the comment service expects.
+// The same checks run against every backend, so MinIO and the filesystem stay interchangeable.
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"github.com/Verce11o/yata-comments/internal/repository"
+	pb "github.com/Verce11o/yata-protos/gen/go/comments"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const uploadURLTTL = time.Minute
+
+type check struct {
+	name string
+	run  func(ctx context.Context, s *suite) error
+}
+
+var checks = []check{
+	{"add and get image", checkAddCommentImage},
+	{"get with limit", checkGetFileLimit},
+	{"add file from reader", checkAddFile},
+	{"copy file", checkCopyFile},
+	{"list files", checkListFiles},
+	{"get file url", checkGetFileURL},
+	{"get file url of a legacy name", checkGetLegacyFileURL},
+	{"upload by presigned url", checkGetUploadURL},
+	{"update image", checkUpdateCommentImage},
+	{"delete file", checkDeleteFile},
+	{"delete missing file", checkDeleteMissingFile},
+	{"quarantine file", checkQuarantineFile},
+}
+
+type suite struct {
+	storage repository.StorageRepository
+	prefix  string
+	names   []string
+}
+
+// Run executes every check against storage and returns all failures joined together.
+// Objects are created under a unique prefix and removed afterwards.
+func Run(ctx context.Context, storage repository.StorageRepository) error {
+	s := &suite{storage: storage, prefix: fmt.Sprintf("conformance/%s/", uuid.NewString())}
+	defer s.cleanup(ctx)
+
+	var errs []error
+
+	for _, c := range checks {
+		if err := c.run(ctx, s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *suite) name(suffix string) string {
+	name := s.prefix + suffix
+	s.names = append(s.names, name)
+
+	return name
+}
+
+func (s *suite) cleanup(ctx context.Context) {
+	for _, name := range s.names {
+		_ = s.storage.DeleteFile(context.WithoutCancel(ctx), name)
+	}
+}
+
+func (s *suite) expectFile(ctx context.Context, name string, want []byte) error {
+	got, err := s.storage.GetFile(ctx, name, int64(len(want)))
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("file %s has %q, want %q", name, got, want)
+	}
+
+	return nil
+}
+
+// expectNotFound checks that a missing file is reported the way the service and handlers detect it.
+func (s *suite) expectNotFound(ctx context.Context, name string) error {
+	_, err := s.storage.GetFile(ctx, name, 1)
+
+	if !errors.Is(err, grpc_errors.ErrNotFound) || !domain.IsNotFound(err) {
+		return fmt.Errorf("got %v, want a not found domain error wrapping %v", err, grpc_errors.ErrNotFound)
+	}
+
+	return nil
+}
+
+func checkAddCommentImage(ctx context.Context, s *suite) error {
+	name := s.name("image.png")
+	data := []byte("image data")
+
+	if err := s.storage.AddCommentImage(ctx, &pb.Image{Chunk: data, ContentType: "image/png", Name: "original.png"}, name); err != nil {
+		return err
+	}
+
+	return s.expectFile(ctx, name, data)
+}
+
+func checkGetFileLimit(ctx context.Context, s *suite) error {
+	name := s.name("limit.bin")
+
+	if err := s.storage.AddFile(ctx, name, strings.NewReader("0123456789")); err != nil {
+		return err
+	}
+
+	got, err := s.storage.GetFile(ctx, name, 4)
+	if err != nil {
+		return err
+	}
+
+	// one byte past the limit is returned so callers can tell the file is too large
+	if len(got) != 5 {
+		return fmt.Errorf("got %d bytes, want 5", len(got))
+	}
+
+	return nil
+}
+
+func checkAddFile(ctx context.Context, s *suite) error {
+	name := s.name("nested/dir/file.bin")
+	data := bytes.Repeat([]byte("x"), 1<<20)
+
+	if err := s.storage.AddFile(ctx, name, bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	return s.expectFile(ctx, name, data)
+}
+
+func checkCopyFile(ctx context.Context, s *suite) error {
+	src := s.name("copy-src.bin")
+	dst := s.name("copy-dst.bin")
+	data := []byte("copied")
+
+	if err := s.storage.AddFile(ctx, src, bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	if err := s.storage.CopyFile(ctx, src, dst); err != nil {
+		return err
+	}
+
+	if err := s.expectFile(ctx, src, data); err != nil {
+		return err
+	}
+
+	return s.expectFile(ctx, dst, data)
+}
+
+func checkListFiles(ctx context.Context, s *suite) error {
+	want := []string{s.name("list/a.bin"), s.name("list/b/c.bin")}
+
+	for _, name := range want {
+		if err := s.storage.AddFile(ctx, name, strings.NewReader(name)); err != nil {
+			return err
+		}
+	}
+
+	found := make(map[string]domain.StoredFile)
+
+	err := s.storage.ListFiles(ctx, func(file domain.StoredFile) error {
+		if strings.HasPrefix(file.Name, s.prefix+"list/") {
+			found[file.Name] = file
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range want {
+		file, ok := found[name]
+		if !ok {
+			return fmt.Errorf("file %s is not listed", name)
+		}
+
+		if file.Size != int64(len(name)) {
+			return fmt.Errorf("file %s has size %d, want %d", name, file.Size, len(name))
+		}
+
+		if file.LastModified.IsZero() {
+			return fmt.Errorf("file %s has no modification time", name)
+		}
+	}
+
+	if len(found) != len(want) {
+		return fmt.Errorf("listed %d files, want %d", len(found), len(want))
+	}
+
+	stop := errors.New("stop")
+	var calls int
+
+	err = s.storage.ListFiles(ctx, func(file domain.StoredFile) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		return fmt.Errorf("listing did not stop at the first error, got %v after %d calls", err, calls)
+	}
+
+	return nil
+}
+
+func checkGetFileURL(ctx context.Context, s *suite) error {
+	return s.checkFileURL(ctx, s.name("url.png"))
+}
+
+// checkGetLegacyFileURL serves a client-named key with characters that must be escaped in URLs.
+func checkGetLegacyFileURL(ctx context.Context, s *suite) error {
+	return s.checkFileURL(ctx, s.name("legacy/my photo #1?100%.png"))
+}
+
+func (s *suite) checkFileURL(ctx context.Context, name string) error {
+	data := []byte("served by url")
+
+	if err := s.storage.AddCommentImage(ctx, &pb.Image{Chunk: data, ContentType: "image/png", Name: "url.png"}, name); err != nil {
+		return err
+	}
+
+	fileURL, err := s.storage.GetFileURL(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status %d", resp.StatusCode)
+	}
+
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(got, data) {
+		return fmt.Errorf("got %q, want %q", got, data)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "image/png" {
+		return fmt.Errorf("got content type %s, want image/png", contentType)
+	}
+
+	return nil
+}
+
+func checkGetUploadURL(ctx context.Context, s *suite) error {
+	name := s.name("upload.jpg")
+	data := []byte("uploaded directly")
+
+	uploadURL, err := s.storage.GetUploadURL(ctx, name, "image/jpeg", int64(len(data)), uploadURLTTL)
+	if err != nil {
+		return err
+	}
+
+	// the URL is bound to the signed content type
+	status, err := put(ctx, uploadURL, "image/png", data)
+	if err != nil {
+		return err
+	}
+
+	if status == http.StatusOK {
+		return errors.New("upload with a different content type was accepted")
+	}
+
+	status, err = put(ctx, uploadURL, "image/jpeg", data)
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		return fmt.Errorf("got status %d", status)
+	}
+
+	return s.expectFile(ctx, name, data)
+}
+
+func checkUpdateCommentImage(ctx context.Context, s *suite) error {
+	oldName := s.name("update-old.png")
+	newName := s.name("update-new.png")
+	data := []byte("new image")
+
+	if err := s.storage.AddFile(ctx, oldName, strings.NewReader("old image")); err != nil {
+		return err
+	}
+
+	if err := s.storage.UpdateCommentImage(ctx, oldName, newName, &pb.Image{Chunk: data, ContentType: "image/png", Name: "new.png"}); err != nil {
+		return err
+	}
+
+	if err := s.expectNotFound(ctx, oldName); err != nil {
+		return fmt.Errorf("old image: %w", err)
+	}
+
+	return s.expectFile(ctx, newName, data)
+}
+
+func checkDeleteFile(ctx context.Context, s *suite) error {
+	name := s.name("delete.bin")
+
+	if err := s.storage.AddFile(ctx, name, strings.NewReader("deleted")); err != nil {
+		return err
+	}
+
+	if err := s.storage.DeleteFile(ctx, name); err != nil {
+		return err
+	}
+
+	return s.expectNotFound(ctx, name)
+}
+
+func checkDeleteMissingFile(ctx context.Context, s *suite) error {
+	return s.storage.DeleteFile(ctx, s.name("missing.bin"))
+}
+
+func checkQuarantineFile(ctx context.Context, s *suite) error {
+	// quarantined files live outside the served storage and are not cleaned up by name
+	name := s.prefix + "quarantine.bin"
+
+	if err := s.storage.QuarantineFile(ctx, name, []byte("infected"), "Eicar-Test-Signature"); err != nil {
+		return err
+	}
+
+	if err := s.expectNotFound(ctx, name); err != nil {
+		return fmt.Errorf("quarantined file is served: %w", err)
+	}
+
+	return nil
+}
+
+func put(ctx context.Context, url string, contentType string, data []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
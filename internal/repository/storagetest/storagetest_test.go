@@ -0,0 +1,64 @@
+package storagetest_test
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/repository/filesystem"
+	"github.com/Verce11o/yata-comments/internal/repository/minio"
+	"github.com/Verce11o/yata-comments/internal/repository/storagetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestFilesystem(t *testing.T) {
+	var handler http.Handler
+
+	// the server is started first, presigned URLs point to its address
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	storage := filesystem.NewCommentFilesystem(t.TempDir(), server.URL, []byte("storagetest-secret"), noop.NewTracerProvider().Tracer(""))
+	handler = storage.Handler()
+
+	if err := storagetest.Run(context.Background(), storage); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMinio runs against the server in STORAGETEST_MINIO_ENDPOINT, the buckets are created when missing.
+func TestMinio(t *testing.T) {
+	endpoint := os.Getenv("STORAGETEST_MINIO_ENDPOINT")
+
+	if endpoint == "" {
+		t.Skip("STORAGETEST_MINIO_ENDPOINT is not set")
+	}
+
+	cfg := &config.Config{MinioConfig: config.MinioConfig{
+		Endpoint:         endpoint,
+		AccessKey:        os.Getenv("STORAGETEST_MINIO_ACCESS_KEY"),
+		SecretKey:        os.Getenv("STORAGETEST_MINIO_SECRET_KEY"),
+		SSL:              os.Getenv("STORAGETEST_MINIO_SSL") == "true",
+		Bucket:           config.Bucket{Name: "storagetest"},
+		QuarantineBucket: "storagetest-quarantine",
+	}}
+
+	ctx := context.Background()
+	client := minio.NewMinio(cfg)
+
+	for _, bucket := range []config.Bucket{cfg.MinioConfig.Bucket, {Name: cfg.MinioConfig.QuarantineBucket}} {
+		if err := minio.EnsureBucket(ctx, client, cfg.MinioConfig.Region, bucket); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	storage := minio.NewCommentMinio(client, cfg.MinioConfig.Bucket.Name, cfg.MinioConfig.QuarantineBucket, noop.NewTracerProvider().Tracer(""))
+
+	if err := storagetest.Run(ctx, storage); err != nil {
+		t.Fatal(err)
+	}
+}
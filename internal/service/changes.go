@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/pagination"
+)
+
+const (
+	changesDefaultLimit = 100
+	changesMaxLimit     = 500
+)
+
+// ListChanges returns changes recorded after token and the token to continue from.
+// The returned token is the given one when nothing changed, so clients can keep polling with it.
+func (t *Comment) ListChanges(ctx context.Context, filter domain.ChangeFilter, token string, limit int) ([]domain.CommentChange, string, error) {
+	ctx, span := t.tracer.Start(ctx, "commentService.ListChanges")
+	defer span.End()
+
+	var afterXactID uint64
+	var afterID int64
+	var err error
+
+	if token != "" {
+		afterXactID, afterID, err = pagination.DecodeChangeToken(token)
+
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// larger pages are capped, the client sees the smaller page and continues from its token
+	if limit <= 0 {
+		limit = changesDefaultLimit
+	} else if limit > changesMaxLimit {
+		limit = changesMaxLimit
+	}
+
+	changes, err := t.repo.GetChanges(ctx, filter, afterXactID, afterID, limit)
+
+	if err != nil {
+		t.log.Errorf("cannot get comment changes in postgres: %v", err.Error())
+		return nil, "", err
+	}
+
+	if len(changes) > 0 {
+		last := changes[len(changes)-1]
+		afterXactID, afterID = last.XactID, last.ChangeID
+	}
+
+	return changes, pagination.EncodeChangeToken(afterXactID, afterID), nil
+}
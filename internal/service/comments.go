@@ -2,49 +2,657 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/commenttext"
+	"github.com/Verce11o/yata-comments/internal/lib/entities"
 	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"github.com/Verce11o/yata-comments/internal/lib/images"
+	"github.com/Verce11o/yata-comments/internal/lib/scanner"
 	"github.com/Verce11o/yata-comments/internal/repository"
 	pb "github.com/Verce11o/yata-protos/gen/go/comments"
+	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	uploadURLTTL        = 15 * time.Minute
+	imageDeleteAttempts = 5
+	imageDeleteBackoff  = time.Second
 )
 
 type Comment struct {
-	log    *zap.SugaredLogger
-	tracer trace.Tracer
-	repo   repository.PostgresRepository
-	redis  repository.RedisRepository
-	minio  repository.MinioRepository
+	log       *zap.SugaredLogger
+	tracer    trace.Tracer
+	repo      repository.PostgresRepository
+	redis     repository.RedisRepository
+	storage   repository.StorageRepository
+	images    *images.Processor
+	scanner   scanner.Scanner
+	directory repository.UserDirectory
+	text      *commenttext.Validator
+	blocklist *blocklistCache
 }
 
-func NewCommentService(log *zap.SugaredLogger, tracer trace.Tracer, repo repository.PostgresRepository, redis repository.RedisRepository, minio repository.MinioRepository) *Comment {
-	return &Comment{log: log, tracer: tracer, repo: repo, redis: redis, minio: minio}
+func NewCommentService(log *zap.SugaredLogger, tracer trace.Tracer, repo repository.PostgresRepository, redis repository.RedisRepository, storage repository.StorageRepository, images *images.Processor, scanner scanner.Scanner, directory repository.UserDirectory, text *commenttext.Validator) *Comment {
+	return &Comment{log: log, tracer: tracer, repo: repo, redis: redis, storage: storage, images: images, scanner: scanner, directory: directory, text: text, blocklist: &blocklistCache{}}
 }
 
-func (t *Comment) CreateComment(ctx context.Context, input *pb.CreateCommentRequest) (string, error) {
+func (t *Comment) CreateComment(ctx context.Context, input *pb.CreateCommentRequest, idempotencyKey string, attachmentsInput domain.AttachmentsInput) (*domain.Comment, error) {
 	ctx, span := t.tracer.Start(ctx, "commentService.CreateComment")
 	defer span.End()
 
-	image := input.GetImage()
+	text, err := t.text.Normalize(input.GetText())
 
-	if image != nil {
+	if err != nil {
+		return nil, err
+	}
 
-		err := t.minio.AddCommentImage(ctx, image, image.GetName())
+	// the normalized text is hashed and stored, so equivalent spellings replay the same comment
+	input.Text = text
+
+	var idempotency *domain.IdempotencyRecord
+
+	if idempotencyKey != "" {
+		idempotency = &domain.IdempotencyRecord{Key: idempotencyKey, RequestHash: createCommentHash(input, attachmentsInput)}
+
+		commentID, err := t.replayCreateComment(ctx, input.GetUserId(), idempotency)
 
 		if err != nil {
-			t.log.Errorf("cannot add image to comment in minio: %v", err.Error())
+			return nil, err
 		}
 
+		if commentID != "" {
+			t.log.Infof("replayed comment %s for idempotency key %s", commentID, idempotencyKey)
+			return t.getReplayedComment(ctx, commentID)
+		}
 	}
 
-	commentID, err := t.repo.CreateComment(ctx, input, image.GetName())
+	if err := t.images.ValidateAltTexts(attachmentsInput.AltTexts); err != nil {
+		return nil, err
+	}
+
+	mentions := t.resolveMentions(ctx, input.GetText(), nil)
+
+	imageInputs, uploads, err := t.getImageInputs(ctx, input.GetUserId(), input.GetImage(), attachmentsInput.UploadTokens)
+
+	if err != nil {
+		t.log.Errorf("cannot get comment images: %v", err.Error())
+		return nil, err
+	}
+
+	attachments, err := t.newAttachments(ctx, input.GetUserId(), imageInputs)
 
 	if err != nil {
+		t.log.Errorf("cannot add comment attachments: %v", err.Error())
+		return nil, err
+	}
+
+	if err := applyAltTexts(attachments, attachmentsInput.AltTexts); err != nil {
+		t.discardAttachments(ctx, attachments)
+		return nil, err
+	}
+
+	comment, err := t.repo.CreateComment(ctx, input, attachments, mentions, entities.Hashtags(input.GetText()), idempotency)
+
+	if err != nil {
+		t.discardAttachments(ctx, attachments)
+	}
+
+	if idempotency != nil && errors.Is(err, grpc_errors.ErrIdempotencyConflict) { // concurrent request with the same key won the race
+		commentID, err := t.replayCreateComment(ctx, input.GetUserId(), idempotency)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return t.getReplayedComment(ctx, commentID)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	t.releaseUploads(ctx, uploads)
+
+	t.setImageURLs(ctx, comment.Attachments)
+
+	t.publishEvent(ctx, domain.CommentCreated, comment)
+	t.notifyMentions(ctx, comment, nil)
+
+	if idempotency != nil {
+		idempotency.CommentID = comment.CommentID.String()
+
+		if err := t.redis.SetIdempotencyKeyCtx(ctx, input.GetUserId(), idempotencyKey, idempotency); err != nil {
+			t.log.Errorf("cannot set idempotency key in redis: %v", err.Error())
+		}
+	}
+
+	return comment, nil
+}
+
+// getReplayedComment loads the comment created by an earlier request with the same idempotency key.
+func (t *Comment) getReplayedComment(ctx context.Context, commentID string) (*domain.Comment, error) {
+	comment, err := t.GetComment(ctx, commentID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// replayCreateComment returns the comment ID previously created with the same idempotency key,
+// or an empty string if the key has not been used yet.
+func (t *Comment) replayCreateComment(ctx context.Context, userID string, idempotency *domain.IdempotencyRecord) (string, error) {
+	record, err := t.redis.GetIdempotencyKeyCtx(ctx, userID, idempotency.Key)
+
+	if err != nil {
+		t.log.Infof("cannot get idempotency key in redis: %v", err.Error())
+
+		record, err = t.repo.GetIdempotencyRecord(ctx, userID, idempotency.Key)
+
+		if domain.IsNotFound(err) {
+			return "", nil
+		}
+
+		if err != nil {
+			t.log.Errorf("cannot get idempotency key in postgres: %v", err.Error())
+			return "", err
+		}
+
+		if err := t.redis.SetIdempotencyKeyCtx(ctx, userID, idempotency.Key, record); err != nil {
+			t.log.Errorf("cannot set idempotency key in redis: %v", err.Error())
+		}
+	}
+
+	if record.RequestHash != idempotency.RequestHash {
+		return "", grpc_errors.ErrIdempotencyKey
+	}
+
+	return record.CommentID, nil
+}
+
+func (t *Comment) UploadImage(ctx context.Context, userID string, originalName string, checksum string, reader io.Reader) (string, error) {
+	ctx, span := t.tracer.Start(ctx, "commentService.UploadImage")
+	defer span.End()
+
+	token := uuid.NewString()
+	objectName := images.UploadName(userID, token)
+	capped := images.NewCappedReader(reader, t.images.MaxSize())
+
+	if err := t.storage.AddFile(ctx, objectName, capped); err != nil {
+		t.log.Errorf("cannot upload image to storage: %v", err.Error())
+		return "", err
+	}
+
+	if checksum != "" && checksum != capped.Checksum() {
+		if err := t.storage.DeleteFile(ctx, objectName); err != nil {
+			t.log.Errorf("cannot delete uploaded image: %v", err.Error())
+		}
+		return "", fmt.Errorf("%w: checksum mismatch", grpc_errors.ErrInvalidImage)
+	}
+
+	upload := &domain.Upload{
+		Token:        token,
+		UserID:       userID,
+		ObjectName:   objectName,
+		OriginalName: originalName,
+		Size:         capped.Size(),
+		Checksum:     capped.Checksum(),
+	}
+
+	if err := t.redis.SetUploadCtx(ctx, upload); err != nil {
+		t.log.Errorf("cannot set upload in redis: %v", err.Error())
 		return "", err
 	}
 
-	return commentID, nil
+	return token, nil
+}
+
+func (t *Comment) CreateUploadURL(ctx context.Context, userID string, originalName string, contentType string, size int64) (*domain.UploadURL, error) {
+	ctx, span := t.tracer.Start(ctx, "commentService.CreateUploadURL")
+	defer span.End()
+
+	if err := t.images.ValidateDeclared(contentType, size); err != nil {
+		return nil, err
+	}
+
+	token := uuid.NewString()
+	objectName := images.UploadName(userID, token)
+
+	uploadURL, err := t.storage.GetUploadURL(ctx, objectName, contentType, size, uploadURLTTL)
+
+	if err != nil {
+		t.log.Errorf("cannot get upload url in storage: %v", err.Error())
+		return nil, err
+	}
+
+	// the checksum stays empty until the object is read back when a comment references it
+	upload := &domain.Upload{
+		Token:        token,
+		UserID:       userID,
+		ObjectName:   objectName,
+		OriginalName: originalName,
+		Size:         size,
+	}
+
+	if err := t.redis.SetUploadCtx(ctx, upload); err != nil {
+		t.log.Errorf("cannot set upload in redis: %v", err.Error())
+		return nil, err
+	}
+
+	return &domain.UploadURL{Token: token, URL: uploadURL, ExpiresAt: time.Now().Add(uploadURLTTL)}, nil
+}
+
+// getImageInputs returns the image attached inline followed by the images referenced by upload tokens.
+func (t *Comment) getImageInputs(ctx context.Context, userID string, image *pb.Image, uploadTokens []string) ([]*pb.Image, []*domain.Upload, error) {
+	var imageInputs []*pb.Image
+
+	if image != nil {
+		imageInputs = append(imageInputs, image)
+	}
+
+	if len(imageInputs)+len(uploadTokens) > t.images.MaxAttachments() {
+		return nil, nil, fmt.Errorf("%w: comment can have at most %d attachments", grpc_errors.ErrInvalidImage, t.images.MaxAttachments())
+	}
+
+	uploads := make([]*domain.Upload, 0, len(uploadTokens))
+
+	for _, token := range uploadTokens {
+		image, upload, err := t.getUpload(ctx, userID, token)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		imageInputs = append(imageInputs, image)
+		uploads = append(uploads, upload)
+	}
+
+	return imageInputs, uploads, nil
+}
+
+// getUpload reads back an image previously uploaded by the same user.
+func (t *Comment) getUpload(ctx context.Context, userID string, uploadToken string) (*pb.Image, *domain.Upload, error) {
+	upload, err := t.redis.GetUploadCtx(ctx, uploadToken)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if upload.UserID != userID {
+		return nil, nil, grpc_errors.ErrPermissionDenied
+	}
+
+	data, err := t.storage.GetFile(ctx, upload.ObjectName, t.images.MaxSize())
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if upload.Checksum != "" && images.Checksum(data) != upload.Checksum {
+		return nil, nil, fmt.Errorf("%w: checksum mismatch", grpc_errors.ErrInvalidImage)
+	}
+
+	return &pb.Image{Chunk: data, Name: upload.OriginalName}, upload, nil
+}
+
+// releaseUploads removes consumed uploads, they cannot be referenced again.
+func (t *Comment) releaseUploads(ctx context.Context, uploads []*domain.Upload) {
+	for _, upload := range uploads {
+		if err := t.redis.DeleteUploadCtx(ctx, upload.Token); err != nil {
+			t.log.Errorf("cannot delete upload in redis: %v", err.Error())
+		}
+
+		if err := t.storage.DeleteFile(ctx, upload.ObjectName); err != nil {
+			t.log.Errorf("cannot delete uploaded image: %v", err.Error())
+		}
+	}
+}
+
+// newAttachments validates and stores the images, the returned attachments are not yet linked to a comment.
+func (t *Comment) newAttachments(ctx context.Context, userID string, imageInputs []*pb.Image) ([]domain.Attachment, error) {
+	attachments := make([]domain.Attachment, 0, len(imageInputs))
+
+	if len(imageInputs) == 0 {
+		return attachments, nil
+	}
+
+	blocklist, err := t.getBlocklist(ctx)
+
+	if err != nil {
+		t.log.Errorf("cannot get image blocklist: %v", err.Error())
+		return nil, err
+	}
+
+	for _, image := range imageInputs {
+		if err := t.scanImage(ctx, userID, image); err != nil {
+			t.discardAttachments(ctx, attachments)
+			return nil, err
+		}
+
+		processed, err := t.images.Process(image.GetChunk())
+
+		if err != nil {
+			t.discardAttachments(ctx, attachments)
+			return nil, err
+		}
+
+		flagged, err := t.checkBlocklist(userID, processed, blocklist)
+
+		if err != nil {
+			t.discardAttachments(ctx, attachments)
+			return nil, err
+		}
+
+		imageName := images.ObjectName(userID, processed.ContentType)
+
+		if err := t.storeImage(ctx, imageName, image.GetName(), processed); err != nil {
+			// the image may be partially stored with some of its variants
+			t.discardAttachments(ctx, append(attachments, domain.Attachment{ImageName: imageName}))
+			return nil, err
+		}
+
+		perceptualHash := int64(processed.PerceptualHash)
+
+		attachments = append(attachments, domain.Attachment{
+			ImageName:      imageName,
+			ContentType:    processed.ContentType,
+			Size:           int64(len(processed.Data)),
+			Width:          processed.Width,
+			Height:         processed.Height,
+			PerceptualHash: &perceptualHash,
+			Flagged:        flagged,
+		})
+	}
+
+	return attachments, nil
+}
+
+// scanImage rejects infected images before anything is stored, they are kept in quarantine for inspection.
+func (t *Comment) scanImage(ctx context.Context, userID string, image *pb.Image) error {
+	result, err := t.scanner.Scan(ctx, image.GetChunk())
+
+	if err != nil {
+		t.log.Errorf("cannot scan image: %v", err.Error())
+		return err
+	}
+
+	if !result.Infected {
+		return nil
+	}
+
+	t.log.Warnf("infected image rejected for user %s: %s", userID, result.Signature)
+
+	if err := t.storage.QuarantineFile(ctx, images.ObjectName(userID, ""), image.GetChunk(), result.Signature); err != nil {
+		t.log.Errorf("cannot quarantine infected image: %v", err.Error())
+	}
+
+	return fmt.Errorf("%w: %s", grpc_errors.ErrInfectedImage, result.Signature)
+}
+
+// checkBlocklist rejects images resembling a blocklisted one, or only flags them when configured so.
+func (t *Comment) checkBlocklist(userID string, image *images.Image, blocklist []domain.BlocklistEntry) (bool, error) {
+	entry := t.images.MatchBlocklist(image.PerceptualHash, blocklist)
+
+	if entry == nil {
+		return false, nil
+	}
+
+	if t.images.FlagBlocklisted() {
+		t.log.Warnf("image of user %s matches blocklist entry %s, flagged for review", userID, entry.EntryID)
+		return true, nil
+	}
+
+	t.log.Warnf("image of user %s matches blocklist entry %s, rejected", userID, entry.EntryID)
+
+	return false, grpc_errors.ErrBlockedImage
+}
+
+// updateAttachments builds the new ordered attachment list of a comment.
+// Without an explicit order, an inline image or uploads replace all current attachments.
+func (t *Comment) updateAttachments(ctx context.Context, userID string, current []domain.Attachment, image *pb.Image, attachmentsInput domain.AttachmentsInput) ([]domain.Attachment, []*domain.Upload, error) {
+	order := attachmentsInput.Order
+
+	if len(order) == 0 {
+		if image == nil && len(attachmentsInput.UploadTokens) == 0 {
+			return current, nil, nil
+		}
+
+		imageInputs, uploads, err := t.getImageInputs(ctx, userID, image, attachmentsInput.UploadTokens)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		attachments, err := t.newAttachments(ctx, userID, imageInputs)
+
+		return attachments, uploads, err
+	}
+
+	if image != nil || len(attachmentsInput.UploadTokens) > 0 {
+		return nil, nil, fmt.Errorf("%w: attachment order cannot be combined with image or upload tokens", grpc_errors.ErrInvalidImage)
+	}
+
+	if len(order) > t.images.MaxAttachments() {
+		return nil, nil, fmt.Errorf("%w: comment can have at most %d attachments", grpc_errors.ErrInvalidImage, t.images.MaxAttachments())
+	}
+
+	existing := make(map[string]domain.Attachment, len(current))
+
+	for _, attachment := range current {
+		existing[attachment.AttachmentID.String()] = attachment
+	}
+
+	attachments := make([]domain.Attachment, 0, len(order))
+	var uploads []*domain.Upload
+
+	for _, entry := range order {
+		if token, ok := strings.CutPrefix(entry, domain.UploadAttachmentPrefix); ok {
+			image, upload, err := t.getUpload(ctx, userID, token)
+
+			if err != nil {
+				t.discardAttachments(ctx, attachments)
+				return nil, nil, err
+			}
+
+			added, err := t.newAttachments(ctx, userID, []*pb.Image{image})
+
+			if err != nil {
+				t.discardAttachments(ctx, attachments)
+				return nil, nil, err
+			}
+
+			attachments = append(attachments, added...)
+			uploads = append(uploads, upload)
+			continue
+		}
+
+		attachment, ok := existing[entry]
+
+		if !ok {
+			t.discardAttachments(ctx, attachments)
+			return nil, nil, fmt.Errorf("%w: attachment %s", grpc_errors.ErrNotFound, entry)
+		}
+
+		delete(existing, entry)
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, uploads, nil
+}
+
+// applyAltTexts sets alt texts positionally, attachments keep their current alt text when none are given.
+func applyAltTexts(attachments []domain.Attachment, altTexts []string) error {
+	if len(altTexts) == 0 {
+		return nil
+	}
+
+	if len(altTexts) != len(attachments) {
+		return fmt.Errorf("%w: got %d alt texts for %d attachments", grpc_errors.ErrInvalidImage, len(altTexts), len(attachments))
+	}
+
+	for i := range attachments {
+		attachments[i].AltText = altTexts[i]
+	}
+
+	return nil
+}
+
+// removedAttachments returns the attachments of before that are no longer present in after.
+func removedAttachments(before []domain.Attachment, after []domain.Attachment) []domain.Attachment {
+	kept := make(map[string]struct{}, len(after))
+
+	for _, attachment := range after {
+		kept[attachment.ImageName] = struct{}{}
+	}
+
+	var removed []domain.Attachment
+
+	for _, attachment := range before {
+		if _, ok := kept[attachment.ImageName]; !ok {
+			removed = append(removed, attachment)
+		}
+	}
+
+	return removed
+}
+
+// storeImage uploads the original image together with all of its configured variants.
+func (t *Comment) storeImage(ctx context.Context, imageName string, originalName string, image *images.Image) error {
+	err := t.storage.AddCommentImage(ctx, &pb.Image{Chunk: image.Data, ContentType: image.ContentType, Name: originalName}, imageName)
+
+	if err != nil {
+		return err
+	}
+
+	variants, err := t.images.Variants(image)
+
+	if err != nil {
+		return err
+	}
+
+	for _, variant := range variants {
+		variantImage := &pb.Image{Chunk: variant.Data, ContentType: variant.ContentType, Name: originalName}
+
+		if err := t.storage.AddCommentImage(ctx, variantImage, images.VariantName(imageName, variant.Name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteImage removes the original image together with all of its configured variants.
+func (t *Comment) deleteImage(ctx context.Context, imageName string) error {
+	if imageName == "" {
+		return nil
+	}
+
+	if err := t.storage.DeleteFile(ctx, imageName); err != nil {
+		return err
+	}
+
+	for _, variantName := range t.images.VariantNames(imageName) {
+		if err := t.storage.DeleteFile(ctx, variantName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// discardAttachments compensates images stored for attachments that were never linked to a comment.
+// Attachments that already have an ID belong to a comment and are left untouched.
+func (t *Comment) discardAttachments(ctx context.Context, attachments []domain.Attachment) {
+	ctx = context.WithoutCancel(ctx)
+
+	for _, attachment := range attachments {
+		if attachment.AttachmentID != uuid.Nil {
+			continue
+		}
+
+		if err := t.deleteImage(ctx, attachment.ImageName); err != nil {
+			t.log.Errorf("cannot discard comment image %s: %v", attachment.ImageName, err.Error())
+		}
+	}
+}
+
+// deleteImagesAsync removes images of attachments the database no longer references.
+// Deletion is retried in the background, whatever is still left behind is collected by ImageGC.
+func (t *Comment) deleteImagesAsync(ctx context.Context, attachments []domain.Attachment) {
+	if len(attachments) == 0 {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		for _, attachment := range attachments {
+			backoff := imageDeleteBackoff
+
+			for attempt := 1; ; attempt++ {
+				err := t.deleteImage(ctx, attachment.ImageName)
+
+				if err == nil {
+					break
+				}
+
+				if attempt == imageDeleteAttempts {
+					t.log.Errorf("cannot delete comment image %s after %d attempts: %v", attachment.ImageName, attempt, err.Error())
+					break
+				}
+
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+		}
+	}()
+}
+
+func (t *Comment) setImageURLs(ctx context.Context, attachments []domain.Attachment) {
+	for i := range attachments {
+		names := t.images.VariantNames(attachments[i].ImageName)
+		names[domain.OriginalImage] = attachments[i].ImageName
+
+		urls := make(map[string]string, len(names))
+
+		for variant, name := range names {
+			url, err := t.storage.GetFileURL(ctx, name)
+
+			if err != nil {
+				t.log.Errorf("cannot get comment image url: %v", err.Error())
+				continue
+			}
+
+			urls[variant] = url
+		}
+
+		attachments[i].URLs = urls
+	}
+}
+
+func createCommentHash(input *pb.CreateCommentRequest, attachmentsInput domain.AttachmentsInput) string {
+	h := sha256.New()
+
+	fields := []string{input.GetTweetId(), input.GetUserId(), input.GetText(), input.GetImage().GetName(), input.GetImage().GetContentType()}
+	fields = append(fields, attachmentsInput.UploadTokens...)
+	fields = append(fields, attachmentsInput.AltTexts...)
+
+	for _, field := range fields {
+		fmt.Fprintf(h, "%d:%s;", len(field), field)
+	}
+	h.Write(input.GetImage().GetChunk())
+
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 func (t *Comment) GetComment(ctx context.Context, commentID string) (domain.Comment, error) {
@@ -53,12 +661,14 @@ func (t *Comment) GetComment(ctx context.Context, commentID string) (domain.Comm
 
 	cachedComment, err := t.redis.GetCommentByIDCtx(ctx, commentID)
 
-	if err != nil {
-		t.log.Infof("cannot get comment by id in redis: %v", err.Error())
+	// the cache is best effort, a failing Redis falls back to Postgres
+	if err != nil && !domain.IsNotFound(err) {
+		t.log.Warnf("cannot get comment by id in redis: %v", err.Error())
 	}
 
 	if cachedComment != nil {
 		t.log.Info("returned cache")
+		setEntities(cachedComment)
 		return *cachedComment, nil
 	}
 
@@ -69,6 +679,9 @@ func (t *Comment) GetComment(ctx context.Context, commentID string) (domain.Comm
 		return domain.Comment{}, err
 	}
 
+	t.setImageURLs(ctx, comment.Attachments)
+	setEntities(comment)
+
 	if err := t.redis.SetByIDCtx(ctx, commentID, comment); err != nil {
 		t.log.Errorf("cannot set comment by id in redis: %v", err.Error())
 	}
@@ -77,7 +690,7 @@ func (t *Comment) GetComment(ctx context.Context, commentID string) (domain.Comm
 
 }
 
-func (t *Comment) GetAllTweetComments(ctx context.Context, input *pb.GetAllTweetCommentsRequest) ([]*pb.Comment, string, error) {
+func (t *Comment) GetAllTweetComments(ctx context.Context, input *pb.GetAllTweetCommentsRequest) ([]domain.Comment, string, error) {
 	ctx, span := t.tracer.Start(ctx, "commentService.GetAllComments")
 	defer span.End()
 
@@ -87,16 +700,30 @@ func (t *Comment) GetAllTweetComments(ctx context.Context, input *pb.GetAllTweet
 
 	if err != nil {
 		t.log.Errorf("cannot get all comments by cursor: %v err: %v", input.GetCursor(), err)
+		return nil, "", err
+	}
+
+	for i := range comments {
+		t.setImageURLs(ctx, comments[i].Attachments)
+		setEntities(&comments[i])
 	}
 
 	return comments, nextCursor, nil
 
 }
 
-func (t *Comment) UpdateComment(ctx context.Context, input *pb.UpdateCommentRequest) (*domain.Comment, error) {
+func (t *Comment) UpdateComment(ctx context.Context, input *pb.UpdateCommentRequest, attachmentsInput domain.AttachmentsInput) (*domain.Comment, error) {
 	ctx, span := t.tracer.Start(ctx, "commentService.UpdateComment")
 	defer span.End()
 
+	text, err := t.text.Normalize(input.GetText())
+
+	if err != nil {
+		return nil, err
+	}
+
+	input.Text = text
+
 	comment, err := t.repo.GetComment(ctx, input.GetCommentId())
 
 	if err != nil {
@@ -109,37 +736,48 @@ func (t *Comment) UpdateComment(ctx context.Context, input *pb.UpdateCommentRequ
 		return nil, grpc_errors.ErrPermissionDenied
 	}
 
-	image := input.GetImage()
-	newImageName := comment.ImageName
+	if err := t.images.ValidateAltTexts(attachmentsInput.AltTexts); err != nil {
+		return nil, err
+	}
 
-	if image != nil { // if input image is not nil, we need to update it
-		var err error
+	mentions := t.resolveMentions(ctx, input.GetText(), comment.Mentions)
 
-		if comment.ImageName == "" {
-			err = t.minio.AddCommentImage(ctx, image, image.GetName())
-		} else {
-			err = t.minio.UpdateCommentImage(ctx, comment.ImageName, image.GetName(), image)
-		}
+	attachments, uploads, err := t.updateAttachments(ctx, input.GetUserId(), comment.Attachments, input.GetImage(), attachmentsInput)
 
-		if err != nil {
-			t.log.Errorf("cannot update comment image: %v", err.Error())
-			return nil, err
-		}
+	if err != nil {
+		t.log.Errorf("cannot update comment attachments: %v", err.Error())
+		return nil, err
+	}
 
-		newImageName = image.GetName()
+	if err := applyAltTexts(attachments, attachmentsInput.AltTexts); err != nil {
+		t.discardAttachments(ctx, attachments)
+		return nil, err
 	}
 
-	newComment, err := t.repo.UpdateComment(ctx, input, newImageName)
+	newComment, err := t.repo.UpdateComment(ctx, input, attachments, mentions, entities.Hashtags(input.GetText()))
 
 	if err != nil {
 		t.log.Errorf("cannot update comment: %v", err.Error())
+		t.discardAttachments(ctx, attachments)
 		return nil, err
 	}
 
+	// old images are removed only once the database points to the new ones
+	t.deleteImagesAsync(ctx, removedAttachments(comment.Attachments, newComment.Attachments))
+
+	t.setImageURLs(ctx, newComment.Attachments)
+	setEntities(newComment)
+
+	t.releaseUploads(ctx, uploads)
+
 	if err := t.redis.DeleteCommentByIDCtx(ctx, comment.CommentID.String()); err != nil {
 		t.log.Errorf("cannot remove comment by id in redis: %v", err.Error())
 	}
 
+	t.publishEvent(ctx, domain.CommentUpdated, newComment)
+
+	t.notifyMentions(ctx, newComment, comment.Mentions)
+
 	return newComment, nil
 }
 
@@ -170,12 +808,9 @@ func (t *Comment) DeleteComment(ctx context.Context, input *pb.DeleteCommentRequ
 		t.log.Errorf("cannot delete comment by id in redis: %v", err.Error())
 	}
 
-	err = t.minio.DeleteFile(ctx, comment.ImageName)
+	t.deleteImagesAsync(ctx, comment.Attachments)
 
-	if err != nil {
-		t.log.Errorf("cannot delete comment image by id: %v", err.Error())
-		return err
-	}
+	t.publishEvent(ctx, domain.CommentDeleted, comment)
 
 	return nil
 
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	commentEventsBatch = 100
+	// commentEventsPoll covers notifications lost while the pub/sub connection reconnects.
+	commentEventsPoll = 30 * time.Second
+
+	// failed publishes are retried in the background with the same event ID
+	commentEventAttempts = 3
+	commentEventBackoff  = 100 * time.Millisecond
+)
+
+// SubscribeTweetComments calls fn for every event of the tweet after cursor until ctx is done or fn fails.
+// An empty cursor starts with events published after the subscription.
+func (t *Comment) SubscribeTweetComments(ctx context.Context, tweetID string, cursor string, fn func(event domain.CommentEvent) error) error {
+	ctx, span := t.tracer.Start(ctx, "commentService.SubscribeTweetComments")
+	defer span.End()
+
+	// subscribing before reading the stream leaves no gap between history and live events
+	notifications, err := t.redis.SubscribeCommentEventsCtx(ctx, tweetID)
+
+	if err != nil {
+		t.log.Errorf("cannot subscribe to comment events in redis: %v", err.Error())
+		return err
+	}
+
+	if cursor == "" {
+		cursor, err = t.redis.GetLastCommentEventCursorCtx(ctx, tweetID)
+
+		if err != nil {
+			t.log.Errorf("cannot get last comment event in redis: %v", err.Error())
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(commentEventsPoll)
+	defer ticker.Stop()
+
+	for {
+		cursor, err = t.sendCommentEvents(ctx, tweetID, cursor, fn)
+
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-notifications:
+			if !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				return grpc_errors.ErrEventsUnavailable
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendCommentEvents passes every stored event after cursor to fn and returns the cursor of the last one.
+func (t *Comment) sendCommentEvents(ctx context.Context, tweetID string, cursor string, fn func(event domain.CommentEvent) error) (string, error) {
+	for {
+		events, err := t.redis.GetCommentEventsCtx(ctx, tweetID, cursor, commentEventsBatch)
+
+		if err != nil {
+			return "", err
+		}
+
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return "", err
+			}
+
+			cursor = event.Cursor
+		}
+
+		if len(events) < commentEventsBatch {
+			return cursor, nil
+		}
+	}
+}
+
+// publishEvent notifies subscribers of the tweet, failures do not fail the change itself.
+// The first attempt runs in the request, a failed one is retried in the background, the stored event
+// ID keeps a retry of an attempt that reached Redis from duplicating it. A retried event can land
+// after a later event of the same comment, subscribers order them by the comment's updated_at.
+func (t *Comment) publishEvent(ctx context.Context, eventType string, comment *domain.Comment) {
+	setEntities(comment)
+
+	// the change is already committed, the request being cancelled does not stop the event
+	ctx = context.WithoutCancel(ctx)
+	event := &domain.CommentEvent{EventID: uuid.New(), Type: eventType, TweetID: comment.TweetID, Comment: *comment}
+
+	if err := t.redis.PublishCommentEventCtx(ctx, event); err == nil {
+		return
+	}
+
+	go t.retryPublishEvent(ctx, event)
+}
+
+func (t *Comment) retryPublishEvent(ctx context.Context, event *domain.CommentEvent) {
+	backoff := commentEventBackoff
+
+	for attempt := 2; ; attempt++ {
+		time.Sleep(backoff)
+		backoff *= 2
+
+		err := t.redis.PublishCommentEventCtx(ctx, event)
+
+		if err == nil {
+			return
+		}
+
+		if attempt == commentEventAttempts {
+			t.log.Errorf("cannot publish comment event in redis after %d attempts: %v", attempt, err.Error())
+			return
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/entities"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+)
+
+// ListHashtagComments returns comments tagged with the hashtag, oldest first, the leading hash sign is optional.
+func (t *Comment) ListHashtagComments(ctx context.Context, tag string, cursor string) ([]domain.Comment, string, error) {
+	ctx, span := t.tracer.Start(ctx, "commentService.ListHashtagComments")
+	defer span.End()
+
+	tag = entities.NormalizeHashtag(tag)
+
+	if tag == "" {
+		return nil, "", grpc_errors.ErrInvalidHashtag
+	}
+
+	comments, nextCursor, err := t.repo.GetHashtagComments(ctx, tag, cursor)
+
+	if err != nil {
+		t.log.Errorf("cannot get hashtag comments in postgres: %v", err.Error())
+		return nil, "", err
+	}
+
+	for i := range comments {
+		t.setImageURLs(ctx, comments[i].Attachments)
+		setEntities(&comments[i])
+	}
+
+	return comments, nextCursor, nil
+}
+
+// setEntities fills the hashtags, URLs and mentions of the comment, mentions must be loaded beforehand.
+func setEntities(comment *domain.Comment) {
+	comment.Entities = entities.ForComment(comment.Text, comment.Mentions)
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/config"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/images"
+	"github.com/Verce11o/yata-comments/internal/repository"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+type ImageGCResult struct {
+	Scanned  int64
+	Orphaned int64
+	Deleted  int64
+	Failed   int64
+}
+
+// ImageGC removes objects from the bucket that no comment references anymore,
+// e.g. uploads of comments whose insert failed or images replaced on update.
+type ImageGC struct {
+	log     *zap.SugaredLogger
+	tracer  trace.Tracer
+	repo    repository.PostgresRepository
+	storage repository.StorageRepository
+	images  *images.Processor
+	cfg     config.ImageGC
+
+	scanned  metric.Int64Counter
+	orphaned metric.Int64Counter
+	deleted  metric.Int64Counter
+	failed   metric.Int64Counter
+}
+
+func NewImageGC(log *zap.SugaredLogger, tracer trace.Tracer, repo repository.PostgresRepository, storage repository.StorageRepository, images *images.Processor, cfg config.ImageGC) *ImageGC {
+	meter := otel.Meter("yata-comments/image-gc")
+
+	return &ImageGC{
+		log:      log,
+		tracer:   tracer,
+		repo:     repo,
+		storage:  storage,
+		images:   images,
+		cfg:      cfg,
+		scanned:  newCounter(log, meter, "image_gc.objects.scanned", "Objects inspected by the image garbage collector"),
+		orphaned: newCounter(log, meter, "image_gc.objects.orphaned", "Unreferenced objects older than the grace period"),
+		deleted:  newCounter(log, meter, "image_gc.objects.deleted", "Orphaned objects deleted from the bucket"),
+		failed:   newCounter(log, meter, "image_gc.objects.failed", "Orphaned objects that could not be deleted"),
+	}
+}
+
+// Run reconciles the bucket every configured interval until ctx is done.
+func (g *ImageGC) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := g.Reconcile(ctx)
+
+			if err != nil {
+				g.log.Errorf("cannot reconcile comment images: %v", err.Error())
+			}
+
+			g.log.Infof("image gc: scanned %d, orphaned %d, deleted %d, failed %d, dry run %t",
+				result.Scanned, result.Orphaned, result.Deleted, result.Failed, g.cfg.DryRun)
+		}
+	}
+}
+
+// Reconcile lists the bucket once and deletes unreferenced objects older than the grace period.
+// In dry-run mode orphans are only logged and counted.
+func (g *ImageGC) Reconcile(ctx context.Context) (ImageGCResult, error) {
+	ctx, span := g.tracer.Start(ctx, "imageGC.Reconcile")
+	defer span.End()
+
+	var result ImageGCResult
+
+	deadline := time.Now().Add(-g.cfg.GracePeriod)
+	batch := make([]domain.StoredFile, 0, g.cfg.BatchSize)
+
+	err := g.storage.ListFiles(ctx, func(file domain.StoredFile) error {
+		result.Scanned++
+
+		if file.LastModified.After(deadline) {
+			return nil
+		}
+
+		batch = append(batch, file)
+
+		if len(batch) < g.cfg.BatchSize {
+			return nil
+		}
+
+		err := g.collect(ctx, batch, &result)
+		batch = batch[:0]
+
+		return err
+	})
+
+	if err == nil && len(batch) > 0 {
+		err = g.collect(ctx, batch, &result)
+	}
+
+	attrs := metric.WithAttributes(attribute.Bool("dry_run", g.cfg.DryRun))
+
+	g.scanned.Add(ctx, result.Scanned, attrs)
+	g.orphaned.Add(ctx, result.Orphaned, attrs)
+	g.deleted.Add(ctx, result.Deleted, attrs)
+	g.failed.Add(ctx, result.Failed, attrs)
+
+	return result, err
+}
+
+func (g *ImageGC) collect(ctx context.Context, files []domain.StoredFile, result *ImageGCResult) error {
+	var names []string
+
+	for _, file := range files {
+		names = append(names, g.images.SourceNames(file.Name)...)
+	}
+
+	referenced, err := g.repo.GetReferencedImageNames(ctx, names)
+
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if g.isReferenced(file.Name, referenced) {
+			continue
+		}
+
+		result.Orphaned++
+
+		if g.cfg.DryRun {
+			g.log.Infof("image gc: would delete %s", file.Name)
+			continue
+		}
+
+		if err := g.storage.DeleteFile(ctx, file.Name); err != nil {
+			g.log.Errorf("cannot delete orphaned image %s: %v", file.Name, err.Error())
+			result.Failed++
+			continue
+		}
+
+		result.Deleted++
+	}
+
+	return nil
+}
+
+// isReferenced treats pending uploads as unreferenced, they are only valid until their token expires.
+func (g *ImageGC) isReferenced(name string, referenced map[string]struct{}) bool {
+	if strings.HasPrefix(name, images.UploadPrefix) {
+		return false
+	}
+
+	for _, source := range g.images.SourceNames(name) {
+		if _, ok := referenced[source]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newCounter(log *zap.SugaredLogger, meter metric.Meter, name string, description string) metric.Int64Counter {
+	counter, err := meter.Int64Counter(name, metric.WithDescription(description))
+
+	if err != nil {
+		log.Errorf("cannot create %s counter: %v", name, err)
+		return noop.Int64Counter{}
+	}
+
+	return counter
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/entities"
+	"github.com/google/uuid"
+)
+
+// resolveMentions finds the mentions of existing users in text, other @words stay plain text.
+// The comment is still written when the user directory is down: usernames resolved for the previous
+// text keep their users and the rest stay unresolved.
+func (t *Comment) resolveMentions(ctx context.Context, text string, previous []domain.Mention) []domain.Mention {
+	parsed := entities.ParseMentions(text)
+
+	if len(parsed) == 0 {
+		return nil
+	}
+
+	usernames := make([]string, 0, len(parsed))
+	seen := make(map[string]struct{}, len(parsed))
+
+	for _, mention := range parsed {
+		if _, ok := seen[mention.Username]; !ok {
+			seen[mention.Username] = struct{}{}
+			usernames = append(usernames, mention.Username)
+		}
+	}
+
+	users, err := t.directory.LookupUsernames(ctx, usernames)
+
+	if err != nil {
+		t.log.Warnf("cannot resolve comment mentions, leaving new ones unresolved: %v", err.Error())
+
+		users = make(map[string]uuid.UUID, len(previous))
+
+		for _, mention := range previous {
+			users[mention.Username] = mention.UserID
+		}
+	}
+
+	var mentions []domain.Mention
+
+	for _, mention := range parsed {
+		userID, ok := users[mention.Username]
+
+		if !ok {
+			continue
+		}
+
+		mentions = append(mentions, domain.Mention{UserID: userID, Username: mention.Username, Start: mention.Start, End: mention.End})
+	}
+
+	return mentions
+}
+
+// notifyMentions emits one event per user mentioned in comment but not in previous, the author is never notified.
+func (t *Comment) notifyMentions(ctx context.Context, comment *domain.Comment, previous []domain.Mention) {
+	notified := make(map[uuid.UUID]struct{}, len(previous)+1)
+	notified[comment.UserID] = struct{}{}
+
+	for _, mention := range previous {
+		notified[mention.UserID] = struct{}{}
+	}
+
+	for _, mention := range comment.Mentions {
+		if _, ok := notified[mention.UserID]; ok {
+			continue
+		}
+
+		notified[mention.UserID] = struct{}{}
+
+		event := &domain.MentionEvent{
+			CommentID:       comment.CommentID,
+			TweetID:         comment.TweetID,
+			AuthorID:        comment.UserID,
+			MentionedUserID: mention.UserID,
+			CreatedAt:       comment.UpdatedAt,
+		}
+
+		if err := t.redis.PublishMentionEventCtx(ctx, event); err != nil {
+			t.log.Errorf("cannot publish mention event in redis: %v", err.Error())
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"github.com/Verce11o/yata-comments/internal/lib/images"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+const blocklistCacheTTL = time.Minute
+
+// BlockImage adds the perceptual hash of a comment's image to the blocklist, the image itself is flagged.
+func (t *Comment) BlockImage(ctx context.Context, attachmentID string, moderatorID string, reason string) (*domain.BlocklistEntry, error) {
+	ctx, span := t.tracer.Start(ctx, "commentService.BlockImage")
+	defer span.End()
+
+	moderator, err := uuid.Parse(moderatorID)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid moderator id", grpc_errors.ErrPermissionDenied)
+	}
+
+	attachment, err := t.repo.GetAttachment(ctx, attachmentID)
+
+	if err != nil {
+		t.log.Errorf("cannot get attachment: %v", err.Error())
+		return nil, err
+	}
+
+	// attachments stored before hashing was introduced are hashed on first use
+	if attachment.PerceptualHash == nil {
+		if err := t.hashAttachment(ctx, attachment); err != nil {
+			t.log.Errorf("cannot hash attachment image: %v", err.Error())
+			return nil, err
+		}
+	}
+
+	entry, err := t.repo.AddBlocklistEntry(ctx, &domain.BlocklistEntry{
+		PerceptualHash:     *attachment.PerceptualHash,
+		SourceAttachmentID: uuid.NullUUID{UUID: attachment.AttachmentID, Valid: true},
+		Reason:             reason,
+		CreatedBy:          moderator,
+	})
+
+	if err != nil {
+		t.log.Errorf("cannot add blocklist entry: %v", err.Error())
+		return nil, err
+	}
+
+	t.blocklist.invalidate()
+
+	return entry, nil
+}
+
+// UnblockImage removes the blocklist entries carrying the exact hash of a comment's image.
+func (t *Comment) UnblockImage(ctx context.Context, attachmentID string) (int64, error) {
+	ctx, span := t.tracer.Start(ctx, "commentService.UnblockImage")
+	defer span.End()
+
+	attachment, err := t.repo.GetAttachment(ctx, attachmentID)
+
+	if err != nil {
+		t.log.Errorf("cannot get attachment: %v", err.Error())
+		return 0, err
+	}
+
+	if attachment.PerceptualHash == nil {
+		return 0, nil
+	}
+
+	removed, err := t.repo.DeleteBlocklistEntries(ctx, *attachment.PerceptualHash)
+
+	if err != nil {
+		t.log.Errorf("cannot delete blocklist entries: %v", err.Error())
+		return 0, err
+	}
+
+	t.blocklist.invalidate()
+
+	return removed, nil
+}
+
+// hashAttachment computes the perceptual hash of the stored image and saves it with the attachment.
+func (t *Comment) hashAttachment(ctx context.Context, attachment *domain.Attachment) error {
+	data, err := t.storage.GetFile(ctx, attachment.ImageName, t.images.MaxSize())
+
+	if err != nil {
+		return err
+	}
+
+	hash, err := images.PerceptualHash(data)
+
+	if err != nil {
+		return err
+	}
+
+	perceptualHash := int64(hash)
+
+	if err := t.repo.SetAttachmentPerceptualHash(ctx, attachment.AttachmentID.String(), perceptualHash); err != nil {
+		return err
+	}
+
+	attachment.PerceptualHash = &perceptualHash
+
+	return nil
+}
+
+// getBlocklist returns the blocklist from memory, it is reloaded once blocklistCacheTTL has passed.
+// Changes made through another replica are picked up on its next reload.
+func (t *Comment) getBlocklist(ctx context.Context) ([]domain.BlocklistEntry, error) {
+	if entries, ok := t.blocklist.get(); ok {
+		return entries, nil
+	}
+
+	entries, err := t.repo.GetBlocklist(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t.blocklist.set(entries)
+
+	return entries, nil
+}
+
+type blocklistCache struct {
+	mu       sync.RWMutex
+	entries  []domain.BlocklistEntry
+	loadedAt time.Time
+}
+
+func (c *blocklistCache) get() ([]domain.BlocklistEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.loadedAt.IsZero() || time.Since(c.loadedAt) > blocklistCacheTTL {
+		return nil, false
+	}
+
+	return c.entries, true
+}
+
+func (c *blocklistCache) set(entries []domain.BlocklistEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = entries
+	c.loadedAt = time.Now()
+}
+
+func (c *blocklistCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.loadedAt = time.Time{}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/Verce11o/yata-comments/internal/domain"
+	"github.com/Verce11o/yata-comments/internal/lib/grpc_errors"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	searchDefaultLimit   = 20
+	searchMaxLimit       = 100
+	searchMaxQueryLength = 256
+)
+
+func (t *Comment) SearchComments(ctx context.Context, query string, filter domain.SearchFilter, cursor string, limit int) ([]domain.SearchResult, string, error) {
+	ctx, span := t.tracer.Start(ctx, "commentService.SearchComments")
+	defer span.End()
+
+	query = strings.TrimSpace(query)
+
+	if query == "" {
+		return nil, "", fmt.Errorf("%w: query is empty", grpc_errors.ErrInvalidSearchQuery)
+	}
+
+	if utf8.RuneCountInString(query) > searchMaxQueryLength {
+		return nil, "", fmt.Errorf("%w: query is longer than %d characters", grpc_errors.ErrInvalidSearchQuery, searchMaxQueryLength)
+	}
+
+	if limit <= 0 || limit > searchMaxLimit {
+		limit = searchDefaultLimit
+	}
+
+	results, nextCursor, err := t.repo.SearchComments(ctx, query, filter, cursor, limit)
+
+	if err != nil {
+		t.log.Errorf("cannot search comments in postgres: %v", err.Error())
+		return nil, "", err
+	}
+
+	for i := range results {
+		t.setImageURLs(ctx, results[i].Comment.Attachments)
+		setEntities(&results[i].Comment)
+	}
+
+	return results, nextCursor, nil
+}
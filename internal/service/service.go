@@ -4,12 +4,21 @@ import (
 	"context"
 	"github.com/Verce11o/yata-comments/internal/domain"
 	pb "github.com/Verce11o/yata-protos/gen/go/comments"
+	"io"
 )
 
 type CommentService interface {
-	CreateComment(ctx context.Context, input *pb.CreateCommentRequest) (string, error)
+	CreateComment(ctx context.Context, input *pb.CreateCommentRequest, idempotencyKey string, attachmentsInput domain.AttachmentsInput) (*domain.Comment, error)
 	GetComment(ctx context.Context, commentID string) (domain.Comment, error)
-	GetAllTweetComments(ctx context.Context, input *pb.GetAllTweetCommentsRequest) ([]*pb.Comment, string, error)
-	UpdateComment(ctx context.Context, input *pb.UpdateCommentRequest) (*domain.Comment, error)
+	GetAllTweetComments(ctx context.Context, input *pb.GetAllTweetCommentsRequest) ([]domain.Comment, string, error)
+	UpdateComment(ctx context.Context, input *pb.UpdateCommentRequest, attachmentsInput domain.AttachmentsInput) (*domain.Comment, error)
 	DeleteComment(ctx context.Context, input *pb.DeleteCommentRequest) error
+	UploadImage(ctx context.Context, userID string, originalName string, checksum string, reader io.Reader) (string, error)
+	CreateUploadURL(ctx context.Context, userID string, originalName string, contentType string, size int64) (*domain.UploadURL, error)
+	BlockImage(ctx context.Context, attachmentID string, moderatorID string, reason string) (*domain.BlocklistEntry, error)
+	UnblockImage(ctx context.Context, attachmentID string) (int64, error)
+	SubscribeTweetComments(ctx context.Context, tweetID string, cursor string, fn func(event domain.CommentEvent) error) error
+	ListChanges(ctx context.Context, filter domain.ChangeFilter, token string, limit int) ([]domain.CommentChange, string, error)
+	SearchComments(ctx context.Context, query string, filter domain.SearchFilter, cursor string, limit int) ([]domain.SearchResult, string, error)
+	ListHashtagComments(ctx context.Context, tag string, cursor string) ([]domain.Comment, string, error)
 }
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS idempotency_key varchar(255) null,
+    ADD COLUMN IF NOT EXISTS request_hash    varchar(64)  null;
+
+CREATE UNIQUE INDEX IF NOT EXISTS comments_user_id_idempotency_key_idx
+    ON comments (user_id, idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS comments_user_id_idempotency_key_idx;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS request_hash,
+    DROP COLUMN IF EXISTS idempotency_key;
+-- +goose StatementEnd
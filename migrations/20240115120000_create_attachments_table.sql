@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS attachments(
+    attachment_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    comment_id    UUID NOT NULL REFERENCES comments (comment_id) ON DELETE CASCADE,
+    position      INT NOT NULL,
+    image_name    varchar(255) NOT NULL,
+    content_type  varchar(255) NOT NULL DEFAULT '',
+    size          BIGINT NOT NULL DEFAULT 0,
+    width         INT NOT NULL DEFAULT 0,
+    height        INT NOT NULL DEFAULT 0,
+    alt_text      varchar(1000) NOT NULL DEFAULT '',
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (comment_id, position)
+);
+
+-- comments.image_name keeps the first attachment so single-image clients keep working
+INSERT INTO attachments (comment_id, position, image_name, created_at)
+SELECT comment_id, 0, image_name, created_at
+FROM comments
+WHERE image_name IS NOT NULL AND image_name <> '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS attachments;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS perceptual_hash BIGINT;
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS flagged BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS image_blocklist(
+    entry_id             UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    perceptual_hash      BIGINT NOT NULL,
+    source_attachment_id UUID REFERENCES attachments (attachment_id) ON DELETE SET NULL,
+    reason               varchar(1000) NOT NULL DEFAULT '',
+    created_by           UUID NOT NULL,
+    created_at           TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS image_blocklist_perceptual_hash_idx ON image_blocklist (perceptual_hash);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS image_blocklist;
+ALTER TABLE attachments DROP COLUMN IF EXISTS flagged;
+ALTER TABLE attachments DROP COLUMN IF EXISTS perceptual_hash;
+-- +goose StatementEnd
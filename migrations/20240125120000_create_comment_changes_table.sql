@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS comment_changes(
+    change_id   BIGSERIAL PRIMARY KEY,
+    xact_id     xid8 NOT NULL DEFAULT pg_current_xact_id(),
+    comment_id  UUID NOT NULL,
+    tweet_id    UUID NOT NULL,
+    user_id     UUID NOT NULL,
+    change_type varchar(16) NOT NULL,
+    changed_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS comment_changes_xact_id_idx ON comment_changes (xact_id, change_id);
+CREATE INDEX IF NOT EXISTS comment_changes_tweet_id_idx ON comment_changes (tweet_id, xact_id, change_id);
+CREATE INDEX IF NOT EXISTS comment_changes_user_id_idx ON comment_changes (user_id, xact_id, change_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS comment_changes;
+-- +goose StatementEnd
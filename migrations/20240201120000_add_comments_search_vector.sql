@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+-- the service keeps the vector up to date in its configured language, the backfill uses the default one
+UPDATE comments SET search_vector = to_tsvector('english'::regconfig,
+    coalesce(text, '') || ' ' || coalesce((SELECT string_agg(alt_text, ' ') FROM attachments WHERE attachments.comment_id = comments.comment_id), ''));
+
+CREATE INDEX IF NOT EXISTS comments_search_vector_idx ON comments USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS comments_search_vector_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS search_vector;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS mentions(
+    comment_id   UUID NOT NULL REFERENCES comments (comment_id) ON DELETE CASCADE,
+    user_id      UUID NOT NULL,
+    username     varchar(64) NOT NULL,
+    start_offset INT NOT NULL,
+    end_offset   INT NOT NULL,
+    PRIMARY KEY (comment_id, start_offset)
+);
+
+CREATE INDEX IF NOT EXISTS mentions_user_id_idx ON mentions (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mentions;
+-- +goose StatementEnd
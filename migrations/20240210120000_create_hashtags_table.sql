@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS hashtags(
+    comment_id UUID NOT NULL REFERENCES comments (comment_id) ON DELETE CASCADE,
+    tag        varchar(100) NOT NULL,
+    PRIMARY KEY (comment_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS hashtags_tag_idx ON hashtags (tag);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS hashtags;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE comments ALTER COLUMN text TYPE text;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE comments ALTER COLUMN text TYPE varchar(255) USING left(text, 255);
+-- +goose StatementEnd